@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"social/docs"
+	"social/internal/auth"
+	"social/internal/auth/oidc"
+	"social/internal/auth/password"
+	"social/internal/mailer"
 	"social/internal/models"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -15,9 +21,15 @@ import (
 )
 
 type application struct {
-	config config
-	models *models.Models
-	logger *zap.SugaredLogger
+	config        config
+	models        *models.Models
+	logger        *zap.SugaredLogger
+	authenticator auth.Authenticator
+	mailer        mailer.Mailer
+	oidcProviders map[string]*oidc.Provider
+	// checked against on logins for unknown emails, which then take as long
+	// as a wrong password does
+	dummyPasswordHash string
 }
 
 type config struct {
@@ -25,6 +37,17 @@ type config struct {
 	db     DBConfig
 	env    string
 	apiURL string
+	// serves the pages the links in emails open; they post the token in the
+	// link to the API, so following a link does not use it up by itself
+	frontendURL string
+	auth        AuthConfig
+	mail        MailConfig
+	outbox      OutboxConfig
+	timeline    TimelineConfig
+	// how long a response stored under an Idempotency-Key is replayed
+	idempotencyTTL time.Duration
+	// signs pagination cursors so clients cannot forge them
+	cursorSecret string
 }
 
 type DBConfig struct {
@@ -32,6 +55,98 @@ type DBConfig struct {
 	maxOpenConns int
 }
 
+type AuthConfig struct {
+	totpIssuer       string
+	token            TokenConfig
+	invite           InviteConfig
+	lockout          LockoutConfig
+	emailRequests    EmailRequestsConfig
+	password         password.Params
+	oidc             OIDCConfig
+	passwordResetExp time.Duration
+	emailChangeExp   time.Duration
+	magicLinkExp     time.Duration
+}
+
+type InviteConfig struct {
+	resendCooldown time.Duration
+	maxSends       int
+}
+
+// LockoutConfig throttles logins per account and per IP. Failures older than
+// window are forgotten; past freeFailures every attempt waits baseDelay,
+// doubling up to maxDelay, and hitting a max*Failures threshold locks the
+// account or IP for duration.
+type LockoutConfig struct {
+	window             time.Duration
+	freeFailures       int
+	baseDelay          time.Duration
+	maxDelay           time.Duration
+	maxAccountFailures int
+	maxIPFailures      int
+	duration           time.Duration
+}
+
+// EmailRequestsConfig throttles the endpoints that mail a token to an address
+// (password resets, magic links). Each address may ask maxPerEmail times and
+// each IP maxPerIP times per window before being refused for the rest of it.
+type EmailRequestsConfig struct {
+	window      time.Duration
+	maxPerEmail int
+	maxPerIP    int
+}
+
+// OIDCConfig lists the identity providers users can sign in with, by the
+// name used in their /auth/oidc/{provider} routes.
+type OIDCConfig struct {
+	providers map[string]oidc.Config
+	stateExp  time.Duration
+}
+
+type MailConfig struct {
+	backend   string
+	fromEmail string
+	logFile   string
+	smtp      SMTPConfig
+}
+
+type SMTPConfig struct {
+	host     string
+	port     int
+	username string
+	password string
+}
+
+// OutboxConfig tunes the outbox dispatcher. Delivered and dead-lettered
+// messages older than retention are deleted every purgeInterval.
+type OutboxConfig struct {
+	pollInterval  time.Duration
+	batchSize     int
+	lease         time.Duration
+	maxAttempts   int
+	baseBackoff   time.Duration
+	maxBackoff    time.Duration
+	retention     time.Duration
+	purgeInterval time.Duration
+}
+
+// TimelineConfig tunes the materialized home timelines. Posts by authors
+// with at least celebrityThreshold followers are not fanned out but read
+// straight from the posts table, and a new follow copies up to backfillLimit
+// of the followed user's latest posts.
+type TimelineConfig struct {
+	celebrityThreshold int
+	backfillLimit      int
+}
+
+type TokenConfig struct {
+	secret     string
+	exp        time.Duration
+	refreshExp time.Duration
+	mfaExp     time.Duration
+	iss        string
+}
+
 func (app *application) router() http.Handler {
 	r := chi.NewRouter()
 
@@ -41,6 +156,9 @@ func (app *application) router() http.Handler {
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
 
+	r.NotFound(app.routeNotFound)
+	r.MethodNotAllowed(app.methodNotAllowed)
+
 	r.Route("/v1", func(r chi.Router) {
 		r.Get("/health", app.health)
 
@@ -48,37 +166,84 @@ func (app *application) router() http.Handler {
 		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
 
 		r.Route("/posts", func(r chi.Router) {
-			r.Post("/", app.createPostHandler)
+			r.Use(app.authTokenMiddleware)
+
+			r.With(app.requireScope(models.ScopePostsWrite), app.idempotencyMiddleware).Post("/", app.createPostHandler)
 
 			r.Route("/{postID}", func(r chi.Router) {
 				r.Use(app.postsContextMiddleware)
 
-				r.Post("/comments", app.createCommentHandler)
+				r.With(app.requireScope(models.ScopeCommentsWrite), app.idempotencyMiddleware).Post("/comments", app.createCommentHandler)
+
+				r.With(app.requireScope(models.ScopeReactionsWrite)).Put("/reaction", app.reactToPostHandler)
+				r.With(app.requireScope(models.ScopeReactionsWrite)).Delete("/reaction", app.unreactToPostHandler)
 
-				r.Get("/", app.getPostHandler)
-				r.Patch("/", app.updatePostHandler)
-				r.Delete("/", app.deletePostHandler)
+				r.With(app.requireScope(models.ScopePostsRead)).Get("/", app.getPostHandler)
+				r.With(app.requireScope(models.ScopePostsWrite), app.requireOwnershipOrRole(models.RoleModerator)).Patch("/", app.updatePostHandler)
+				r.With(app.requireScope(models.ScopePostsWrite), app.requireOwnershipOrRole(models.RoleAdmin)).Delete("/", app.deletePostHandler)
 			})
 		})
 
 		r.Route("/users", func(r chi.Router) {
+			r.Use(app.authTokenMiddleware)
+
+			r.Route("/me", func(r chi.Router) {
+				r.Use(app.requireSession)
+
+				r.Post("/email", app.changeEmailHandler)
+
+				r.Post("/mfa/totp", app.enrolTOTPHandler)
+				r.Post("/mfa/totp/confirm", app.confirmTOTPHandler)
+				r.Delete("/mfa/totp", app.disableTOTPHandler)
+
+				r.Get("/sessions", app.getSessionsHandler)
+				r.Delete("/sessions", app.deleteOtherSessionsHandler)
+				r.Delete("/sessions/{sessionID}", app.deleteSessionHandler)
+
+				r.Get("/tokens", app.getPersonalAccessTokensHandler)
+				r.Post("/tokens", app.createPersonalAccessTokenHandler)
+				r.Delete("/tokens/{tokenID}", app.deletePersonalAccessTokenHandler)
+			})
+
 			r.Route("/{userID}", func(r chi.Router) {
 				r.Use(app.userContextMiddleware)
 
-				r.Get("/", app.getUserHandler)
+				r.With(app.requireScope(models.ScopeUsersRead)).Get("/", app.getUserHandler)
 
-				r.Put("/follow", app.followUserHandler)
-				r.Put("/unfollow", app.unfollowUserHandler)
+				r.With(app.requireScope(models.ScopeUsersWrite), app.idempotencyMiddleware).Put("/follow", app.followUserHandler)
+				r.With(app.requireScope(models.ScopeUsersWrite), app.idempotencyMiddleware).Put("/unfollow", app.unfollowUserHandler)
 			})
 
 			r.Group(func(r chi.Router) {
+				r.Use(app.requireScope(models.ScopeFeedRead))
+
 				r.Get("/feed", app.getUserFeedHandler)
 			})
 		})
 
+		r.Group(func(r chi.Router) {
+			r.Use(app.authTokenMiddleware)
+
+			r.With(app.requireScope(models.ScopePostsRead)).Get("/search", app.searchHandler)
+			r.With(app.requireScope(models.ScopeFeedRead)).Get("/timelines/public", app.getPublicTimelineHandler)
+			r.With(app.requireScope(models.ScopePostsRead)).Get("/tags/{tag}/posts", app.getTagPostsHandler)
+		})
+
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/signup", app.signupHandler)
 			r.Post("/activate/{token}", app.activateHandler)
+			r.Post("/invites/resend", app.resendInviteHandler)
+			r.Post("/password/forgot", app.forgotPasswordHandler)
+			r.Post("/password/reset", app.resetPasswordHandler)
+			r.Post("/email/confirm/{token}", app.confirmEmailChangeHandler)
+			r.Post("/login", app.loginHandler)
+			r.Post("/login/mfa", app.loginMFAHandler)
+			r.Post("/magic-link", app.requestMagicLinkHandler)
+			r.Post("/magic-link/{token}", app.magicLinkLoginHandler)
+			r.Post("/refresh", app.refreshHandler)
+			r.Post("/logout", app.logoutHandler)
+			r.Get("/oidc/{provider}/start", app.startOIDCHandler)
+			r.Get("/oidc/{provider}/callback", app.oidcCallbackHandler)
 		})
 	})
 
@@ -86,6 +251,10 @@ func (app *application) router() http.Handler {
 }
 
 func (app *application) run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go app.runOutboxDispatcher(ctx)
 
 	docs.SwaggerInfo.Version = version
 	docs.SwaggerInfo.Host = app.config.apiURL
@@ -102,3 +271,21 @@ func (app *application) run() error {
 	log.Printf("starting server on %s", app.config.addr)
 	return server.ListenAndServe()
 }
+
+// externalURL builds an absolute link to path on the public API address.
+func (app *application) externalURL(path string) string {
+	return absoluteURL(app.config.apiURL, path)
+}
+
+// frontendURL builds an absolute link to path on the frontend, for use in
+// emails.
+func (app *application) frontendURL(path string) string {
+	return absoluteURL(app.config.frontendURL, path)
+}
+
+func absoluteURL(base, path string) string {
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	return strings.TrimSuffix(base, "/") + path
+}
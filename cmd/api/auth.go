@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"social/internal/auth/password"
 	"social/internal/models"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
@@ -14,7 +17,7 @@ import (
 type signupPayload struct {
 	Username string `json:"username" validate:"required,max=20,min=3"`
 	Email    string `json:"email" validate:"email,required"`
-	Password string `json:"password" validate:"min=5,max=20"`
+	Password string `json:"password" validate:"required,min=12,max=128"`
 }
 
 // signupHandler godoc
@@ -42,12 +45,17 @@ func (app *application) signupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := password.Check("password", form.Password, form.Username, form.Email); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
 	userID, err := uuid.NewV7()
 	if err != nil {
 		app.errorServerError(w, r, err)
 		return
 	}
-	hashedPassword, err := hashPassword(form.Password)
+	hashedPassword, err := app.hashPassword(form.Password)
 	if err != nil {
 		app.errorServerError(w, r, err)
 		return
@@ -63,8 +71,7 @@ func (app *application) signupHandler(w http.ResponseWriter, r *http.Request) {
 		app.errorServerError(w, r, err)
 		return
 	}
-	// todo: send an email to the user for the invite
-	// ---------------------------------------------
+
 	if err := app.jsonResponse(w, http.StatusCreated, user); err != nil {
 		app.errorServerError(w, r, err)
 		return
@@ -82,6 +89,7 @@ func (app *application) signupHandler(w http.ResponseWriter, r *http.Request) {
 //	@Success		200		{object}	nil
 //	@Failure		400		{object}	ErrorResponse
 //	@Failure		404		{object}	ErrorResponse
+//	@Failure		410		{object}	ErrorResponse
 //	@Failure		500		{object}	ErrorResponse
 //	@Router			/auth/activate/{token} [post]
 func (app *application) activateHandler(w http.ResponseWriter, r *http.Request) {
@@ -92,42 +100,369 @@ func (app *application) activateHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	invite := &models.Invite{InviteToken: parsedToken}
-	if err := app.models.Invites.GetInvite(r.Context(), invite); err != nil {
+	if err := app.models.Users.ActivateByInviteToken(r.Context(), parsedToken); err != nil {
 		switch {
-		case errors.Is(err, pgx.ErrNoRows):
+		case errors.Is(err, models.ErrInviteNotFound):
 			app.errorNotFound(w, r, err)
 			return
+		case errors.Is(err, models.ErrInviteExpired):
+			app.errorGone(w, r, err)
+			return
 		default:
 			app.errorServerError(w, r, err)
 			return
 		}
 	}
-	if invite.ExpiresAt.Before(time.Now()) {
-		app.errorBadRequest(w, r, errors.New("token expired"))
+
+	if err = app.jsonResponse(w, http.StatusOK, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+const (
+	accessTokenType = "access"
+	mfaTokenType    = "mfa_pending"
+)
+
+type loginPayload struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+// tokenResponse holds a signed access token and the refresh token used to renew it
+// swagger:model tokenResponse
+type tokenResponse struct {
+	// Signed JWT to send in the Authorization header as "Bearer <token>"
+	Token string `json:"token,omitempty"`
+	// Opaque single-use token exchanged at /auth/refresh for a new token pair
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// Set when the password was accepted but a second factor is still needed
+	MFARequired bool `json:"mfa_required,omitempty"`
+	// Short-lived token to send with the second factor to /auth/login/mfa
+	MFAToken string `json:"mfa_token,omitempty"`
+}
+
+type refreshPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=100"`
+}
+
+// loginHandler godoc
+//
+//	@Summary		Log in a user
+//	@Description	Exchanges an activated user's credentials for an access token and a refresh token. Users with two-factor authentication get an mfa_token to complete the login at /auth/login/mfa instead. Repeated failures slow down and then temporarily lock the account and the client IP.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		loginPayload	true	"Login payload"
+//	@Success		200		{object}	DataResponseToken
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		429		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/login [post]
+func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
+	var form loginPayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
 		return
 	}
 
-	// todo: could just create a database call that activates a user based on ID
 	ctx := r.Context()
-	user, err := app.models.Users.Get(ctx, invite.UserID)
+	ip := clientIP(r)
+	retryAfter, err := app.loginRetryAfter(ctx, form.Email, ip)
 	if err != nil {
 		app.errorServerError(w, r, err)
 		return
 	}
-	user.IsActivated = true
-	// TODO: wrap update and delete in a transaction
-	err = app.models.Users.Update(ctx, user)
+	if retryAfter > 0 {
+		app.errorTooManyRequests(w, r, "too many failed login attempts", retryAfter)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(ctx, form.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			_ = checkPassword(app.dummyPasswordHash, form.Password)
+			if err := app.recordLoginFailure(ctx, form.Email, ip, nil); err != nil {
+				app.errorServerError(w, r, err)
+				return
+			}
+			app.errorUnauthorized(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := checkPassword(user.Password, form.Password); err != nil {
+		if err := app.recordLoginFailure(ctx, form.Email, ip, user); err != nil {
+			app.errorServerError(w, r, err)
+			return
+		}
+		app.errorUnauthorized(w, r, err)
+		return
+	}
+
+	if !user.IsActivated {
+		app.errorUnauthorized(w, r, errors.New("user is not activated"))
+		return
+	}
+
+	app.upgradePasswordHash(ctx, user, form.Password)
+
+	tokens, err := app.loginTokens(r, user.ID)
 	if err != nil {
 		app.errorServerError(w, r, err)
 		return
 	}
-	err = app.models.Invites.Delete(ctx, user.ID)
+	// with two-factor authentication the account counter stays until the
+	// second factor is passed too
+	if !tokens.MFARequired {
+		if err := app.clearLoginFailures(ctx, user.Email); err != nil {
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// refreshHandler godoc
+//
+//	@Summary		Refresh tokens
+//	@Description	Rotates a refresh token into a new access/refresh token pair. Reusing a rotated token revokes its whole family.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		refreshPayload	true	"Refresh payload"
+//	@Success		200		{object}	DataResponseToken
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/refresh [post]
+func (app *application) refreshHandler(w http.ResponseWriter, r *http.Request) {
+	var form refreshPayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	refreshToken, err := app.models.RefreshTokens.Rotate(r.Context(), form.RefreshToken, app.config.auth.token.refreshExp)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRefreshTokenInvalid):
+			app.errorUnauthorized(w, r, err)
+			return
+		case errors.Is(err, models.ErrRefreshTokenReused):
+			app.logger.Warnw("refresh token reuse detected, token family revoked", "ip", r.RemoteAddr)
+			app.errorUnauthorized(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	accessToken, err := app.generateAccessToken(refreshToken.UserID, refreshToken.FamilyID)
 	if err != nil {
 		app.errorServerError(w, r, err)
 		return
 	}
-	if err = app.jsonResponse(w, http.StatusOK, nil); err != nil {
+
+	tokens := tokenResponse{Token: accessToken, RefreshToken: refreshToken.Plaintext}
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// logoutHandler godoc
+//
+//	@Summary		Log out
+//	@Description	Revokes the refresh token and every token rotated from the same login
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			request	body	refreshPayload	true	"Refresh payload"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/logout [post]
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	var form refreshPayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := app.models.RefreshTokens.RevokeFamily(r.Context(), form.RefreshToken); err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// upgradePasswordHash re-hashes a just verified password when its stored hash
+// uses an older algorithm or parameters. Failing to do so is not worth failing
+// the login over.
+func (app *application) upgradePasswordHash(ctx context.Context, user *models.User, plaintext string) {
+	if !password.NeedsRehash([]byte(user.Password), app.config.auth.password) {
+		return
+	}
+	hash, err := app.hashPassword(plaintext)
+	if err == nil {
+		err = app.models.Users.UpdatePassword(ctx, user.ID, hash)
+	}
+	if err != nil {
+		app.logger.Errorw("error upgrading password hash", "user_id", user.ID, "error", err)
+	}
+}
+
+// loginTokens finishes a login whose first factor succeeded: users with
+// two-factor authentication get an mfa token, everyone else a new session.
+func (app *application) loginTokens(r *http.Request, userID uuid.UUID) (tokenResponse, error) {
+	totp, err := app.models.MFA.GetTOTP(r.Context(), userID)
+	if err != nil && !errors.Is(err, models.ErrTOTPNotEnrolled) {
+		return tokenResponse{}, err
+	}
+
+	if totp != nil && totp.Enabled() {
+		mfaToken, err := app.generateMFAToken(userID)
+		if err != nil {
+			return tokenResponse{}, err
+		}
+		return tokenResponse{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+	return app.issueTokens(r, userID)
+}
+
+// issueTokens starts a new session for the user on the requesting device and
+// returns the first refresh token of that session with a fresh access token.
+func (app *application) issueTokens(r *http.Request, userID uuid.UUID) (tokenResponse, error) {
+	sessionID, err := uuid.NewV7()
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	session := &models.Session{
+		ID:        sessionID,
+		UserID:    userID,
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	}
+	refreshToken, err := models.NewRefreshToken(userID, sessionID, app.config.auth.token.refreshExp)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	if err := app.models.Sessions.Create(r.Context(), session, refreshToken); err != nil {
+		return tokenResponse{}, err
+	}
+
+	accessToken, err := app.generateAccessToken(userID, sessionID)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	return tokenResponse{Token: accessToken, RefreshToken: refreshToken.Plaintext}, nil
+}
+
+func (app *application) generateAccessToken(userID, sessionID uuid.UUID) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": userID.String(),
+		"sid": sessionID.String(),
+		"typ": accessTokenType,
+		"exp": now.Add(app.config.auth.token.exp).Unix(),
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"iss": app.config.auth.token.iss,
+		"aud": app.config.auth.token.iss,
+	}
+	return app.authenticator.GenerateToken(claims)
+}
+
+// generateMFAToken issues the token proving that a user passed the password
+// step of a login. It cannot be used as an access token.
+func (app *application) generateMFAToken(userID uuid.UUID) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": userID.String(),
+		"typ": mfaTokenType,
+		"exp": now.Add(app.config.auth.token.mfaExp).Unix(),
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"iss": app.config.auth.token.iss,
+		"aud": app.config.auth.token.iss,
+	}
+	return app.authenticator.GenerateToken(claims)
+}
+
+type resendInvitePayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// resendInviteHandler godoc
+//
+//	@Summary		Resend an activation invite
+//	@Description	Issues a new activation token for a pending account and emails it again. The response is the same whether or not the email belongs to a pending account.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		resendInvitePayload	true	"Resend payload"
+//	@Success		202		{object}	nil
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/invites/resend [post]
+func (app *application) resendInviteHandler(w http.ResponseWriter, r *http.Request) {
+	var form resendInvitePayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	cfg := app.config.auth.invite
+	err := app.models.Invites.Resend(r.Context(), form.Email, cfg.resendCooldown, cfg.maxSends)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInviteNotFound),
+			errors.Is(err, models.ErrInviteCooldown),
+			errors.Is(err, models.ErrInviteLimitReached):
+			// answered like a successful resend so callers cannot probe for accounts
+			app.logger.Infow("invite not resent", "reason", err)
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, nil); err != nil {
 		app.errorServerError(w, r, err)
 	}
 }
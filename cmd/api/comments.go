@@ -25,12 +25,16 @@ type commentPayload struct {
 //	@Tags			Comments
 //	@Accept			json
 //	@Produce		json
-//	@Param			postID	path		string			true	"Post ID (UUID)"
-//	@Param			request	body		commentPayload	true	"Comment payload"
-//	@Success		201		{object}	DataResponseComment
-//	@Failure		400		{object}	ErrorResponse
-//	@Failure		404		{object}	ErrorResponse
-//	@Failure		500		{object}	ErrorResponse
+//	@Param			postID			path		string			true	"Post ID (UUID)"
+//	@Param			request			body		commentPayload	true	"Comment payload"
+//	@Param			Idempotency-Key	header		string			false	"Makes retries of this request return the first response"
+//	@Success		201				{object}	DataResponseComment
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		401				{object}	ErrorResponse
+//	@Failure		403				{object}	ErrorResponse
+//	@Failure		404				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Security		ApiKeyAuth
 //	@Router			/posts/{postID}/comments [post]
 func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Request) {
 	cp := commentPayload{}
@@ -47,8 +51,7 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// todo: get the userID from request context
-	userID := uuid.MustParse("b58e1f73-028f-4c17-b8ac-8a3b416c69fd")
+	user := getAuthUserFromContext(r)
 
 	commentUID, err := uuid.NewV7()
 	if err != nil {
@@ -60,7 +63,7 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		ID:      commentUID,
 		Content: cp.Content,
 		PostID:  cp.PostID,
-		UserID:  userID,
+		UserID:  user.ID,
 	}
 
 	if err := app.models.Comments.CreateComment(r.Context(), &comment); err != nil {
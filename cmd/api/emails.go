@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"social/internal/models"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type changeEmailPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// changeEmailHandler godoc
+//
+//	@Summary		Change email address
+//	@Description	Sends a confirmation link to the new address and a notice to the current one. The address only changes once confirmed.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		changeEmailPayload	true	"Change email payload"
+//	@Success		202		{object}	nil
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		409		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/email [post]
+func (app *application) changeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var form changeEmailPayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	user := getAuthUserFromContext(r)
+	if strings.EqualFold(form.Email, user.Email) {
+		app.errorBadRequest(w, r, errors.New("email is already the current address"))
+		return
+	}
+
+	if _, err := app.models.EmailChanges.Create(r.Context(), user, form.Email, app.config.auth.emailChangeExp); err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicateEmail):
+			app.errorConflict(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// confirmEmailChangeHandler godoc
+//
+//	@Summary		Confirm an email change
+//	@Description	Applies a pending email change using the token sent to the new address
+//	@Tags			Auth
+//	@Produce		json
+//	@Param			token	path		string	true	"Email change token"
+//	@Success		200		{object}	nil
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		409		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/email/confirm/{token} [post]
+func (app *application) confirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	if _, err := app.models.EmailChanges.Confirm(r.Context(), token); err != nil {
+		switch {
+		case errors.Is(err, models.ErrEmailChangeInvalid):
+			app.errorBadRequest(w, r, err)
+			return
+		case errors.Is(err, models.ErrDuplicateEmail):
+			app.errorConflict(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
@@ -1,21 +1,220 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"social/internal/auth/password"
+	"social/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+)
+
+// Stable machine readable error codes. Clients branch on these, so existing
+// values must not change.
+const (
+	codeBadRequest       = "bad_request"
+	codeInvalidJSON      = "invalid_json"
+	codeValidationFailed = "validation_failed"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeConflict         = "conflict"
+	codePreconditionFail = "precondition_failed"
+	codePreconditionReq  = "precondition_required"
+	codeGone             = "gone"
+	codeUnprocessable    = "unprocessable_entity"
+	codeTooManyRequests  = "too_many_requests"
+	codeInternal         = "internal_error"
 )
 
+const problemContentType = "application/problem+json"
+
+func newProblem(r *http.Request, status int, code, detail string) ErrorResponse {
+	return ErrorResponse{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+}
+
+func sendProblem(w http.ResponseWriter, p ErrorResponse) error {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, fieldErrors map[string]string) error {
+	p := newProblem(r, status, code, detail)
+	p.Errors = fieldErrors
+	return sendProblem(w, p)
+}
+
+// errorBadRequest explains what was wrong with the request. Decoder and
+// validator errors are translated into messages keyed by JSON field name
+// instead of leaking their Go internals.
 func (app *application) errorBadRequest(w http.ResponseWriter, r *http.Request, err error) {
 	app.logger.Errorf("%s: %s: %s error: %s\n", http.StatusText(http.StatusBadRequest), r.Method, r.URL.Path, err)
-	_ = WriteJSONError(w, http.StatusBadRequest, fmt.Sprintf("%s", err))
+	code, detail, fieldErrors := describeBadRequest(err)
+	_ = writeProblem(w, r, http.StatusBadRequest, code, detail, fieldErrors)
 }
 
 func (app *application) errorServerError(w http.ResponseWriter, r *http.Request, err error) {
 	app.logger.Errorf("%s: %s: %s error: %s\n", http.StatusText(http.StatusInternalServerError), r.Method, r.URL.Path, err)
-	_ = WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("%s", http.StatusText(http.StatusInternalServerError)))
+	_ = writeProblem(w, r, http.StatusInternalServerError, codeInternal, "the server encountered a problem and could not process the request", nil)
 }
 
 func (app *application) errorNotFound(w http.ResponseWriter, r *http.Request, err error) {
 	app.logger.Errorf("%s: %s: %s error: %s\n", http.StatusText(http.StatusNotFound), r.Method, r.URL.Path, err)
-	_ = WriteJSONError(w, http.StatusNotFound, fmt.Sprintf("%s", http.StatusText(http.StatusNotFound)))
+	_ = writeProblem(w, r, http.StatusNotFound, codeNotFound, "the requested resource could not be found", nil)
+}
+
+func (app *application) errorUnauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnf("%s: %s: %s error: %s\n", http.StatusText(http.StatusUnauthorized), r.Method, r.URL.Path, err)
+	_ = writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "missing or invalid credentials", nil)
+}
+
+func (app *application) errorGone(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Errorf("%s: %s: %s error: %s\n", http.StatusText(http.StatusGone), r.Method, r.URL.Path, err)
+	_ = writeProblem(w, r, http.StatusGone, codeGone, err.Error(), nil)
+}
+
+func (app *application) errorConflict(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Errorf("%s: %s: %s error: %s\n", http.StatusText(http.StatusConflict), r.Method, r.URL.Path, err)
+	_ = writeProblem(w, r, http.StatusConflict, codeConflict, err.Error(), nil)
+}
+
+func (app *application) errorUnprocessableEntity(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnf("%s: %s: %s error: %s\n", http.StatusText(http.StatusUnprocessableEntity), r.Method, r.URL.Path, err)
+	_ = writeProblem(w, r, http.StatusUnprocessableEntity, codeUnprocessable, err.Error(), nil)
+}
+
+func (app *application) errorForbidden(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnf("%s: %s: %s error: %s\n", http.StatusText(http.StatusForbidden), r.Method, r.URL.Path, err)
+	_ = writeProblem(w, r, http.StatusForbidden, codeForbidden, err.Error(), nil)
+}
+
+// errorEditConflict answers a write whose If-Match does not name the current
+// version of a post with the post as it is now, and its ETag, so the client
+// can merge and retry.
+func (app *application) errorEditConflict(w http.ResponseWriter, r *http.Request, current *models.Post) {
+	app.logger.Warnf("%s: %s: %s error: %s\n", http.StatusText(http.StatusPreconditionFailed), r.Method, r.URL.Path, models.ErrEditConflict)
+	w.Header().Set("ETag", postETag(current))
+	p := newProblem(r, http.StatusPreconditionFailed, codePreconditionFail, "the post has been modified since it was read, retry with its current version")
+	p.Current = current
+	_ = sendProblem(w, p)
+}
+
+// errorPreconditionRequired answers a conditional write sent without the
+// precondition it needs.
+func (app *application) errorPreconditionRequired(w http.ResponseWriter, r *http.Request, header string) {
+	app.logger.Warnf("%s: %s: %s missing %s\n", http.StatusText(http.StatusPreconditionRequired), r.Method, r.URL.Path, header)
+	_ = writeProblem(w, r, http.StatusPreconditionRequired, codePreconditionReq, fmt.Sprintf("the %s header is required", header), nil)
+}
+
+// errorTooManyRequests answers a throttled request; reason says what was
+// throttled, as in "too many failed login attempts".
+func (app *application) errorTooManyRequests(w http.ResponseWriter, r *http.Request, reason string, retryAfter time.Duration) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	app.logger.Warnf("%s: %s: %s retry after: %ds\n", http.StatusText(http.StatusTooManyRequests), r.Method, r.URL.Path, seconds)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	_ = writeProblem(w, r, http.StatusTooManyRequests, codeTooManyRequests, fmt.Sprintf("%s, retry in %d seconds", reason, seconds), nil)
+}
+
+// routeNotFound and methodNotAllowed replace chi's plain text defaults.
+func (app *application) routeNotFound(w http.ResponseWriter, r *http.Request) {
+	_ = writeProblem(w, r, http.StatusNotFound, codeNotFound, "the requested resource could not be found", nil)
+}
+
+func (app *application) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	_ = writeProblem(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, fmt.Sprintf("the %s method is not supported for this resource", r.Method), nil)
+}
+
+func describeBadRequest(err error) (code, detail string, fieldErrors map[string]string) {
+	var (
+		validationErrs validator.ValidationErrors
+		policyErr      *password.PolicyError
+		syntaxErr      *json.SyntaxError
+		typeErr        *json.UnmarshalTypeError
+		maxBytesErr    *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &validationErrs):
+		fieldErrors = make(map[string]string, len(validationErrs))
+		for _, fe := range validationErrs {
+			fieldErrors[fe.Field()] = validationMessage(fe)
+		}
+		return codeValidationFailed, "one or more fields are invalid", fieldErrors
+	case errors.As(err, &policyErr):
+		return codeValidationFailed, "one or more fields are invalid", map[string]string{policyErr.Field: policyErr.Message}
+	case errors.As(err, &syntaxErr):
+		return codeInvalidJSON, fmt.Sprintf("body contains badly-formed JSON (at character %d)", syntaxErr.Offset), nil
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return codeInvalidJSON, "body contains badly-formed JSON", nil
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return codeInvalidJSON, "body contains a value of the wrong type", map[string]string{typeErr.Field: "must be a " + typeErr.Type.String()}
+		}
+		return codeInvalidJSON, fmt.Sprintf("body contains a value of the wrong type (at character %d)", typeErr.Offset), nil
+	case errors.Is(err, io.EOF):
+		return codeInvalidJSON, "body must not be empty", nil
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return codeInvalidJSON, "body contains an unknown field", map[string]string{field: "is not a recognised field"}
+	case errors.As(err, &maxBytesErr):
+		return codeInvalidJSON, fmt.Sprintf("body must not be larger than %d bytes", maxBytesErr.Limit), nil
+	default:
+		return codeBadRequest, err.Error(), nil
+	}
+}
+
+// validationMessage turns a failed validator tag into a sentence about the
+// field, for the tags the payloads in this package use.
+func validationMessage(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return "is required unless its alternative is provided"
+	case "email":
+		return "must be a valid email address"
+	case "uuid":
+		return "must be a valid UUID"
+	case "numeric":
+		return "must contain only digits"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "len":
+		if isString {
+			return fmt.Sprintf("must be exactly %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must contain exactly %s items", fe.Param())
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must contain at least %s items", fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must contain at most %s items", fe.Param())
+	default:
+		return fmt.Sprintf("failed the %q check", fe.Tag())
+	}
 }
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"social/internal/auth"
+	"social/internal/auth/password"
+	"social/internal/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// newTestApplication returns an application backed by in-memory fakes. Models
+// a test does not set are nil, so touching them fails loudly.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	cfg := config{
+		env:    "development",
+		apiURL: "localhost:4000",
+		auth: AuthConfig{
+			token: TokenConfig{
+				secret:     "test-secret",
+				exp:        time.Minute * 15,
+				refreshExp: time.Hour,
+				mfaExp:     time.Minute * 5,
+				iss:        "social",
+			},
+			lockout: LockoutConfig{
+				window:             time.Minute * 15,
+				freeFailures:       100,
+				baseDelay:          time.Second,
+				maxDelay:           time.Second * 30,
+				maxAccountFailures: 3,
+				maxIPFailures:      100,
+				duration:           time.Minute * 15,
+			},
+			// cheap enough to hash on every test login
+			password: password.Params{
+				Memory:      1024,
+				Iterations:  1,
+				Parallelism: 1,
+				SaltLength:  16,
+				KeyLength:   32,
+			},
+			emailRequests: EmailRequestsConfig{
+				window:      time.Hour,
+				maxPerEmail: 2,
+				maxPerIP:    100,
+			},
+			oidc: OIDCConfig{
+				stateExp: time.Minute * 10,
+			},
+		},
+	}
+
+	dummyPasswordHash, err := password.Hash("not the password of any user", cfg.auth.password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := newFakeUsers()
+	return &application{
+		config: cfg,
+		models: &models.Models{
+			Users:         users,
+			Identities:    &fakeIdentities{users: users, links: make(map[string]uuid.UUID)},
+			OIDCStates:    &fakeOIDCStates{states: make(map[string]models.OIDCState)},
+			MFA:           fakeMFA{},
+			Sessions:      fakeSessions{},
+			LoginAttempts: models.NewInMemoryLoginAttempts(),
+		},
+		logger:            zap.NewNop().Sugar(),
+		authenticator:     auth.NewJWTAuthenticator(cfg.auth.token.secret, cfg.auth.token.iss, cfg.auth.token.iss),
+		dummyPasswordHash: string(dummyPasswordHash),
+	}
+}
+
+type fakeUsers struct {
+	models.UsersInterface
+
+	mu    sync.Mutex
+	users map[uuid.UUID]*models.User
+}
+
+func newFakeUsers() *fakeUsers {
+	return &fakeUsers{users: make(map[uuid.UUID]*models.User)}
+}
+
+func (f *fakeUsers) add(user models.User) *models.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	f.users[user.ID] = &user
+	return &user
+}
+
+func (f *fakeUsers) Get(_ context.Context, id uuid.UUID) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (f *fakeUsers) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, user := range f.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f *fakeUsers) UpdatePassword(_ context.Context, id uuid.UUID, hash []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, ok := f.users[id]; ok {
+		user.Password = string(hash)
+	}
+	return nil
+}
+
+type fakeIdentities struct {
+	users *fakeUsers
+	// beforeCreateUser runs at the start of CreateUser, to stage races with
+	// other sign-ups.
+	beforeCreateUser func(*models.User)
+
+	mu      sync.Mutex
+	links   map[string]uuid.UUID
+	revoked []uuid.UUID
+}
+
+func (f *fakeIdentities) GetUserID(_ context.Context, provider, subject string) (uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	userID, ok := f.links[provider+"/"+subject]
+	if !ok {
+		return uuid.Nil, models.ErrIdentityNotFound
+	}
+	return userID, nil
+}
+
+func (f *fakeIdentities) Link(_ context.Context, identity *models.Identity, placeholderPassword []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := identity.Provider + "/" + identity.Subject
+	if _, ok := f.links[key]; ok {
+		return models.ErrIdentityAlreadyLinked
+	}
+	f.links[key] = identity.UserID
+
+	f.users.mu.Lock()
+	defer f.users.mu.Unlock()
+	if user := f.users.users[identity.UserID]; !user.IsActivated {
+		user.IsActivated = true
+		user.Password = string(placeholderPassword)
+		f.revoked = append(f.revoked, user.ID)
+	}
+	return nil
+}
+
+func (f *fakeIdentities) CreateUser(ctx context.Context, user *models.User, identity *models.Identity) error {
+	if f.beforeCreateUser != nil {
+		f.beforeCreateUser(user)
+	}
+	if _, err := f.users.GetByEmail(ctx, user.Email); err == nil {
+		return models.ErrDuplicateEmail
+	}
+	f.users.add(*user)
+	identity.UserID = user.ID
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := identity.Provider + "/" + identity.Subject
+	if _, ok := f.links[key]; ok {
+		return models.ErrIdentityAlreadyLinked
+	}
+	f.links[key] = identity.UserID
+	return nil
+}
+
+type fakeOIDCStates struct {
+	mu     sync.Mutex
+	states map[string]models.OIDCState
+}
+
+func (f *fakeOIDCStates) Create(_ context.Context, state *models.OIDCState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.states[state.Plaintext] = *state
+	return nil
+}
+
+func (f *fakeOIDCStates) Consume(_ context.Context, provider, plaintext string) (*models.OIDCState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state, ok := f.states[plaintext]
+	delete(f.states, plaintext)
+	if !ok || state.Provider != provider || state.ExpiresAt.Before(time.Now()) {
+		return nil, models.ErrOIDCStateInvalid
+	}
+	return &state, nil
+}
+
+type fakeMFA struct {
+	models.MFAInterface
+}
+
+func (fakeMFA) GetTOTP(context.Context, uuid.UUID) (*models.TOTP, error) {
+	return nil, models.ErrTOTPNotEnrolled
+}
+
+type fakeSessions struct {
+	models.SessionsInterface
+}
+
+func (fakeSessions) Create(context.Context, *models.Session, *models.RefreshToken) error {
+	return nil
+}
@@ -1,56 +1,213 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
+	"slices"
 	"social/internal/models"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/go-chi/chi/v5"
 )
 
 // getUserFeedHandler godoc
 //
 //	@Summary		Get user feed
-//	@Description	Returns a paginated feed of posts from the user and followed users
+//	@Description	Returns a paginated feed of posts from the user and followed users, newest first or, with mode=top, highest ranked first. Pages are addressed by offset, or by the next_cursor/prev_cursor of a previous page, which stays stable while new posts arrive.
 //	@Tags			Feed
 //	@Produce		json
-//	@Param			limit	query		int			false	"Items per page"		minimum(1)	maximum(20)
-//	@Param			offset	query		int			false	"Offset for pagination"	minimum(0)
-//	@Param			sort	query		string		false	"Sort order"			Enums(ASC,DESC)
+//	@Param			limit	query		int			false	"Items per page"								minimum(1)	maximum(20)
+//	@Param			offset	query		int			false	"Offset for pagination, ignored with a cursor"	minimum(0)
+//	@Param			cursor	query		string		false	"Cursor from a previous page's pagination"
+//	@Param			sort	query		string		false	"Sort order, ignored in top mode"																														Enums(ASC,DESC)
+//	@Param			mode	query		string		false	"latest orders by creation time, top ranks the last week's posts by a time-decayed score of comments, reactions and your interactions with the author"	Enums(latest,top)
 //	@Param			tags	query		[]string	false	"Filter by tags (comma separated)"
 //	@Param			search	query		string		false	"Search in title/content"
 //	@Param			from	query		string		false	"From date RFC3339"	example("2024-01-02T15:04:05Z")
 //	@Param			to		query		string		false	"To date RFC3339"	example("2024-12-31T23:59:59Z")
 //	@Success		200		{object}	DataResponseFeed
 //	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
 //	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
 //	@Router			/users/feed [get]
 func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Request) {
-	// todo: replace this with user from context
-	userID := uuid.MustParse("bd3319ea-d197-46cf-9b79-4a03e136c22d")
+	user := getAuthUserFromContext(r)
 
+	pg, ok := app.readFeedQuery(w, r)
+	if !ok {
+		return
+	}
+
+	posts, more, err := app.models.Posts.Feed(r.Context(), user.ID, pg)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err = app.paginatedResponse(w, http.StatusOK, posts, app.feedPagination(pg, posts, more)); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// getPublicTimelineHandler godoc
+//
+//	@Summary		Get the public timeline
+//	@Description	Returns a paginated timeline of posts from all users, newest first or, with mode=top, highest ranked first. It takes the same filters and pagination as the feed.
+//	@Tags			Feed
+//	@Produce		json
+//	@Param			limit	query		int			false	"Items per page"								minimum(1)	maximum(20)
+//	@Param			offset	query		int			false	"Offset for pagination, ignored with a cursor"	minimum(0)
+//	@Param			cursor	query		string		false	"Cursor from a previous page's pagination"
+//	@Param			sort	query		string		false	"Sort order, ignored in top mode"																														Enums(ASC,DESC)
+//	@Param			mode	query		string		false	"latest orders by creation time, top ranks the last week's posts by a time-decayed score of comments, reactions and your interactions with the author"	Enums(latest,top)
+//	@Param			tags	query		[]string	false	"Filter by tags (comma separated)"
+//	@Param			search	query		string		false	"Search in title/content"
+//	@Param			from	query		string		false	"From date RFC3339"	example("2024-01-02T15:04:05Z")
+//	@Param			to		query		string		false	"To date RFC3339"	example("2024-12-31T23:59:59Z")
+//	@Success		200		{object}	DataResponseFeed
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/timelines/public [get]
+func (app *application) getPublicTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	user := getAuthUserFromContext(r)
+
+	pg, ok := app.readFeedQuery(w, r)
+	if !ok {
+		return
+	}
+
+	posts, more, err := app.models.Posts.Public(r.Context(), user.ID, pg)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err = app.paginatedResponse(w, http.StatusOK, posts, app.feedPagination(pg, posts, more)); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// getTagPostsHandler godoc
+//
+//	@Summary		Get the posts of a tag
+//	@Description	Returns a paginated timeline of posts from all users tagged with the tag, newest first or, with mode=top, highest ranked first. It takes the same filters and pagination as the feed; tags narrows it down to posts that also carry those tags.
+//	@Tags			Feed
+//	@Produce		json
+//	@Param			tag		path		string		true	"Tag"
+//	@Param			limit	query		int			false	"Items per page"								minimum(1)	maximum(20)
+//	@Param			offset	query		int			false	"Offset for pagination, ignored with a cursor"	minimum(0)
+//	@Param			cursor	query		string		false	"Cursor from a previous page's pagination"
+//	@Param			sort	query		string		false	"Sort order, ignored in top mode"																														Enums(ASC,DESC)
+//	@Param			mode	query		string		false	"latest orders by creation time, top ranks the last week's posts by a time-decayed score of comments, reactions and your interactions with the author"	Enums(latest,top)
+//	@Param			tags	query		[]string	false	"Filter by further tags (comma separated)"
+//	@Param			search	query		string		false	"Search in title/content"
+//	@Param			from	query		string		false	"From date RFC3339"	example("2024-01-02T15:04:05Z")
+//	@Param			to		query		string		false	"To date RFC3339"	example("2024-12-31T23:59:59Z")
+//	@Success		200		{object}	DataResponseFeed
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/posts [get]
+func (app *application) getTagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getAuthUserFromContext(r)
+
+	tag, err := url.PathUnescape(chi.URLParam(r, "tag"))
+	if err != nil || tag == "" {
+		app.errorBadRequest(w, r, errors.New("tag must be a non-empty path segment"))
+		return
+	}
+
+	pg, ok := app.readFeedQuery(w, r)
+	if !ok {
+		return
+	}
+	if !slices.Contains(pg.Tags, tag) {
+		pg.Tags = append(pg.Tags, tag)
+	}
+
+	posts, more, err := app.models.Posts.Public(r.Context(), user.ID, pg)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err = app.paginatedResponse(w, http.StatusOK, posts, app.feedPagination(pg, posts, more)); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// readFeedQuery parses and validates the filters and pagination shared by the
+// feed and the timelines, answering the request itself when they are invalid.
+func (app *application) readFeedQuery(w http.ResponseWriter, r *http.Request) (models.PaginatedFeedQuery, bool) {
 	pg, err := models.PaginatedFeedQuery{
 		Limit:  20,
 		Offset: 0,
 		Sort:   "DESC",
+		Mode:   models.FeedModeLatest,
 	}.Parse(r)
 	if err != nil {
 		app.errorBadRequest(w, r, err)
-		return
+		return pg, false
 	}
 
 	err = Validate.Struct(&pg)
 	if err != nil {
 		app.errorBadRequest(w, r, err)
-		return
+		return pg, false
 	}
 
-	posts, err := app.models.Posts.Feed(r.Context(), userID, pg)
-	if err != nil {
-		app.errorServerError(w, r, err)
-		return
+	if pg.Mode == models.FeedModeTop {
+		pg.Sort = "DESC"
+		pg.AsOf = time.Now()
 	}
 
-	if err = app.jsonResponse(w, http.StatusOK, posts); err != nil {
-		app.errorServerError(w, r, err)
+	if err := pg.DecodeCursor([]byte(app.config.cursorSecret)); err != nil {
+		app.errorBadRequest(w, r, err)
+		return pg, false
+	}
+	return pg, true
+}
+
+// feedPagination builds the cursors around a page of posts. more tells
+// whether posts remain in the direction the page was read in; in the other
+// direction there are posts whenever the page was reached from a cursor or a
+// non-zero offset.
+func (app *application) feedPagination(pg models.PaginatedFeedQuery, posts []models.FeedPost, more bool) Pagination {
+	var pagination Pagination
+	if len(posts) == 0 {
+		return pagination
+	}
+
+	cursor := func(post models.FeedPost, direction string) *string {
+		c := models.FeedCursor{
+			CreatedAt: post.CreatedAt,
+			ID:        post.ID,
+			Direction: direction,
+			Sort:      pg.Sort,
+			Mode:      pg.Mode,
+		}
+		if pg.Mode == models.FeedModeTop && post.Score != nil {
+			c.Score = *post.Score
+			c.AsOf = pg.AsOf
+		}
+		encoded := c.Encode([]byte(app.config.cursorSecret))
+		return &encoded
+	}
+
+	backwards := pg.Position != nil && pg.Position.Direction == models.CursorPrev
+	if more || backwards {
+		pagination.NextCursor = cursor(posts[len(posts)-1], models.CursorNext)
+	}
+	if (backwards && more) || (!backwards && (pg.Position != nil || pg.Offset > 0)) {
+		pagination.PrevCursor = cursor(posts[0], models.CursorPrev)
 	}
+	return pagination
 }
@@ -1,7 +1,25 @@
 package main
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"net"
+	"net/http"
+	"social/internal/auth/password"
+)
 
-func hashPassword(password string) ([]byte, error) {
-	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (app *application) hashPassword(plaintext string) ([]byte, error) {
+	return password.Hash(plaintext, app.config.auth.password)
+}
+
+func checkPassword(hash, plaintext string) error {
+	return password.Verify([]byte(hash), plaintext)
+}
+
+// clientIP returns the caller's address as resolved by middleware.RealIP,
+// without the port the connection was made from.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
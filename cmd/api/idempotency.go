@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"social/internal/models"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+)
+
+// replayedHeaders are the response headers stored with an idempotent response
+// and sent again when it is replayed.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// idempotencyMiddleware makes requests carrying an Idempotency-Key header
+// safe to retry: the first response for a user's key is stored and replayed
+// to later requests with the same key and body. A key reused with a different
+// request is rejected with 422, and one whose first request is still running
+// with 409. Responses with a 5xx status are not kept, so the request can be
+// retried for real.
+//
+// Responses are stored as sent, so the middleware is only mounted on routes
+// creating content. It must never wrap one whose response carries a secret,
+// such as a new token or TOTP enrolment.
+func (app *application) idempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		user := getAuthUserFromContext(r)
+		if key == "" || user == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			app.errorBadRequest(w, r, errors.New("Idempotency-Key must be at most 255 characters"))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1_048_578))
+		if err != nil {
+			app.errorBadRequest(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		record := &models.IdempotencyKey{
+			UserID:      user.ID,
+			Key:         key,
+			RequestHash: requestFingerprint(r, body),
+			ExpiresAt:   time.Now().Add(app.config.idempotencyTTL),
+		}
+		existing, err := app.models.IdempotencyKeys.Reserve(ctx, record)
+		if err != nil && !errors.Is(err, models.ErrIdempotencyKeyExists) {
+			app.errorServerError(w, r, err)
+			return
+		}
+		if existing != nil {
+			switch {
+			case !bytes.Equal(existing.RequestHash, record.RequestHash):
+				app.errorUnprocessableEntity(w, r, errors.New("Idempotency-Key was already used for a different request"))
+			case existing.StatusCode == nil:
+				app.errorConflict(w, r, errors.New("a request with this Idempotency-Key is still being processed"))
+			default:
+				replayResponse(w, existing)
+			}
+			return
+		}
+
+		var captured bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&captured)
+
+		defer func() {
+			// the request context may already be cancelled by now
+			ctx := context.WithoutCancel(ctx)
+			panicked := recover()
+			status := ww.Status()
+			if status == 0 && panicked == nil {
+				status = http.StatusOK
+			}
+			if panicked != nil || status >= http.StatusInternalServerError {
+				if err := app.models.IdempotencyKeys.Release(ctx, user.ID, key); err != nil {
+					app.logger.Errorw("error releasing idempotency key", "user_id", user.ID, "error", err)
+				}
+				if panicked != nil {
+					panic(panicked)
+				}
+				return
+			}
+
+			record.StatusCode = &status
+			record.ResponseHeaders = make(map[string]string)
+			for _, name := range replayedHeaders {
+				if value := ww.Header().Get(name); value != "" {
+					record.ResponseHeaders[name] = value
+				}
+			}
+			record.ResponseBody = captured.Bytes()
+			if err := app.models.IdempotencyKeys.Complete(ctx, record); err != nil {
+				app.logger.Errorw("error storing idempotent response", "user_id", user.ID, "error", err)
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// requestFingerprint identifies what a request asks for, so a key cannot be
+// replayed against another endpoint or payload.
+func requestFingerprint(r *http.Request, body []byte) []byte {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hash.Sum(nil)
+}
+
+func replayResponse(w http.ResponseWriter, record *models.IdempotencyKey) {
+	for name, value := range record.ResponseHeaders {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(idempotencyReplayedHeader, "true")
+	w.WriteHeader(*record.StatusCode)
+	_, _ = w.Write(record.ResponseBody)
+}
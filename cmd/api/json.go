@@ -3,7 +3,9 @@ package main
 import (
 	"encoding/json"
 	"net/http"
+	"reflect"
 	"social/internal/models"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -12,14 +14,47 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+	// report validation errors under the names clients send, not Go's
+	Validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
 }
 
-// ErrorResponse represents a standard error message returned by the API.
+// ErrorResponse is the RFC 7807 problem details body, served as
+// application/problem+json, that every error of the API comes with.
 // swagger:model ErrorResponse
 type ErrorResponse struct {
-	// Message describing the error
-	// example: invalid request payload
-	Error string `json:"error"`
+	// URI identifying the problem type
+	// example: about:blank
+	Type string `json:"type"`
+	// Short summary of the problem type, the HTTP status text
+	// example: Bad Request
+	Title string `json:"title"`
+	// HTTP status code
+	// example: 400
+	Status int `json:"status"`
+	// Explanation specific to this occurrence
+	// example: one or more fields are invalid
+	Detail string `json:"detail,omitempty"`
+	// Path of the request that failed
+	// example: /v1/auth/signup
+	Instance string `json:"instance,omitempty"`
+	// Stable machine readable error code
+	// example: validation_failed
+	Code string `json:"code"`
+	// ID of the request, to quote when reporting a problem
+	RequestID string `json:"request_id,omitempty"`
+	// Reason each rejected field failed, keyed by its JSON name
+	Errors map[string]string `json:"errors,omitempty"`
+	// Current state of the resource, when a write lost an edit conflict
+	Current any `json:"current,omitempty"`
 }
 
 // DataResponsePost wraps a Post in the standard data envelope.
@@ -28,10 +63,21 @@ type DataResponsePost struct {
 	Data models.Post `json:"data"`
 }
 
-// DataResponseFeed wraps a list of feed posts in the standard data envelope.
+// Pagination links a page of a list to its neighbours. A cursor is null when
+// there is no page in that direction.
+// swagger:model Pagination
+type Pagination struct {
+	// Opaque cursor of the following page
+	NextCursor *string `json:"next_cursor"`
+	// Opaque cursor of the preceding page
+	PrevCursor *string `json:"prev_cursor"`
+}
+
+// DataResponseFeed wraps a page of feed posts in the standard data envelope.
 // swagger:model DataResponseFeed
 type DataResponseFeed struct {
-	Data []models.FeedPost `json:"data"`
+	Data       []models.FeedPost `json:"data"`
+	Pagination Pagination        `json:"pagination"`
 }
 
 // DataResponseComment wraps a Comment in the standard data envelope.
@@ -46,6 +92,48 @@ type DataResponseUser struct {
 	Data models.User `json:"data"`
 }
 
+// DataResponseToken wraps an access token in the standard data envelope.
+// swagger:model DataResponseToken
+type DataResponseToken struct {
+	Data tokenResponse `json:"data"`
+}
+
+// DataResponseSessions wraps a list of sessions in the standard data envelope.
+// swagger:model DataResponseSessions
+type DataResponseSessions struct {
+	Data []models.Session `json:"data"`
+}
+
+// DataResponseTOTPEnrolment wraps a TOTP enrolment in the standard data envelope.
+// swagger:model DataResponseTOTPEnrolment
+type DataResponseTOTPEnrolment struct {
+	Data totpEnrolmentResponse `json:"data"`
+}
+
+// DataResponseRecoveryCodes wraps recovery codes in the standard data envelope.
+// swagger:model DataResponseRecoveryCodes
+type DataResponseRecoveryCodes struct {
+	Data recoveryCodesResponse `json:"data"`
+}
+
+// DataResponsePersonalAccessToken wraps a personal access token in the standard data envelope.
+// swagger:model DataResponsePersonalAccessToken
+type DataResponsePersonalAccessToken struct {
+	Data models.PersonalAccessToken `json:"data"`
+}
+
+// DataResponsePersonalAccessTokens wraps a list of personal access tokens in the standard data envelope.
+// swagger:model DataResponsePersonalAccessTokens
+type DataResponsePersonalAccessTokens struct {
+	Data []models.PersonalAccessToken `json:"data"`
+}
+
+// DataResponseSearch wraps a list of search results in the standard data envelope.
+// swagger:model DataResponseSearch
+type DataResponseSearch struct {
+	Data []models.SearchResult `json:"data"`
+}
+
 func writeJSON(w http.ResponseWriter, status int, data any) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -62,8 +150,12 @@ func readJSON(w http.ResponseWriter, r *http.Request, data any) error {
 	return decoder.Decode(data)
 }
 
-func WriteJSONError(w http.ResponseWriter, status int, message string) error {
-	return writeJSON(w, status, ErrorResponse{Error: message})
+func (app *application) paginatedResponse(w http.ResponseWriter, status int, data any, pagination Pagination) error {
+	type pageWrapper struct {
+		Data       any        `json:"data"`
+		Pagination Pagination `json:"pagination"`
+	}
+	return writeJSON(w, status, pageWrapper{Data: data, Pagination: pagination})
 }
 
 func (app *application) jsonResponse(w http.ResponseWriter, status int, data any) error {
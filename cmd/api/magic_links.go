@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"social/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type magicLinkPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// requestMagicLinkHandler godoc
+//
+//	@Summary		Request a sign-in link
+//	@Description	Emails a single-use, short-lived login link to an activated account. The link opens the frontend sign-in page, which redeems the token. The response is the same whether or not the email belongs to an account. Requests are limited per address and per client IP.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		magicLinkPayload	true	"Magic link payload"
+//	@Success		202		{object}	nil
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		429		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/magic-link [post]
+func (app *application) requestMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	var form magicLinkPayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	retryAfter, err := app.throttleEmailRequest(ctx, "magic_link", form.Email, clientIP(r))
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+	if retryAfter > 0 {
+		app.errorTooManyRequests(w, r, "too many sign-in link requests", retryAfter)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(ctx, form.Email)
+	switch {
+	case err == nil && user.IsActivated:
+		link, err := models.NewMagicLink(user.ID, clientIP(r), r.UserAgent(), app.config.auth.magicLinkExp)
+		if err != nil {
+			app.errorServerError(w, r, err)
+			return
+		}
+		if err := app.models.MagicLinks.Create(ctx, user, link); err != nil {
+			app.errorServerError(w, r, err)
+			return
+		}
+	case err == nil, errors.Is(err, pgx.ErrNoRows):
+		// answered like a successful request so callers cannot probe for accounts
+	default:
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// magicLinkLoginHandler godoc
+//
+//	@Summary		Log in with a sign-in link
+//	@Description	Redeems a magic link for the same tokens as a password login, including the two-factor step for users who enabled it
+//	@Tags			Auth
+//	@Produce		json
+//	@Param			token	path		string	true	"Magic link token"
+//	@Success		200		{object}	DataResponseToken
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/magic-link/{token} [post]
+func (app *application) magicLinkLoginHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	link, err := app.models.MagicLinks.Consume(ctx, chi.URLParam(r, "token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrMagicLinkInvalid):
+			app.errorUnauthorized(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	user, err := app.models.Users.Get(ctx, link.UserID)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+	if !user.IsActivated {
+		app.errorUnauthorized(w, r, errors.New("user is not activated"))
+		return
+	}
+
+	tokens, err := app.loginTokens(r, user.ID)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
@@ -2,9 +2,17 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"os"
+	"social/internal/auth"
+	"social/internal/auth/oidc"
+	"social/internal/auth/password"
 	"social/internal/env"
+	"social/internal/mailer"
 	"social/internal/models"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
@@ -34,17 +42,95 @@ const version = "0.0.1"
 // @securityDefinitions.apikey	ApiKeyAuth
 // @in							header
 // @name						Authorization
-// @description
+// @description				Type "Bearer" followed by a space and the JWT access token.
 func main() {
+	environment := env.GetString("ENV", "development")
+	tokenSecret, err := secretFromEnv(environment, "AUTH_TOKEN_SECRET")
+	if err != nil {
+		log.Fatal(err)
+	}
+	cursorSecret, err := secretFromEnv(environment, "CURSOR_SECRET")
+	if err != nil {
+		log.Fatal(err)
+	}
+	argon2Params, err := argon2ParamsFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	addr := env.GetString("ADDR", ":8080")
 	cfg := config{
-		addr:   addr,
-		env:    env.GetString("ENV", "development"),
-		apiURL: env.GetString("EXTERNAL_URL", "localhost:8080"),
+		addr:        addr,
+		env:         environment,
+		apiURL:      env.GetString("EXTERNAL_URL", "localhost:8080"),
+		frontendURL: env.GetString("FRONTEND_URL", "localhost:3000"),
 		db: DBConfig{
 			dsn:          env.GetString("DATABASE_URL", ""),
 			maxOpenConns: 30,
 		},
+		auth: AuthConfig{
+			totpIssuer: "Social",
+			token: TokenConfig{
+				secret:     tokenSecret,
+				exp:        time.Minute * 15,
+				refreshExp: time.Hour * 24 * 30,
+				mfaExp:     time.Minute * 5,
+				iss:        "social",
+			},
+			invite: InviteConfig{
+				resendCooldown: time.Minute * 5,
+				maxSends:       5,
+			},
+			lockout: LockoutConfig{
+				window:             time.Minute * 15,
+				freeFailures:       3,
+				baseDelay:          time.Second,
+				maxDelay:           time.Second * 30,
+				maxAccountFailures: env.GetInt("LOGIN_MAX_ACCOUNT_FAILURES", 10),
+				maxIPFailures:      env.GetInt("LOGIN_MAX_IP_FAILURES", 50),
+				duration:           time.Minute * 15,
+			},
+			emailRequests: EmailRequestsConfig{
+				window:      time.Hour,
+				maxPerEmail: env.GetInt("EMAIL_REQUESTS_MAX_PER_EMAIL", 3),
+				maxPerIP:    env.GetInt("EMAIL_REQUESTS_MAX_PER_IP", 20),
+			},
+			password: argon2Params,
+			oidc: OIDCConfig{
+				providers: oidcProvidersFromEnv(),
+				stateExp:  time.Minute * 10,
+			},
+			passwordResetExp: time.Hour,
+			emailChangeExp:   time.Hour * 24,
+			magicLinkExp:     time.Minute * 15,
+		},
+		mail: MailConfig{
+			backend:   env.GetString("MAILER", "log"),
+			fromEmail: env.GetString("FROM_EMAIL", "no-reply@social.local"),
+			logFile:   env.GetString("MAILER_LOG_FILE", ""),
+			smtp: SMTPConfig{
+				host:     env.GetString("SMTP_HOST", "localhost"),
+				port:     env.GetInt("SMTP_PORT", 1025),
+				username: env.GetString("SMTP_USERNAME", ""),
+				password: env.GetString("SMTP_PASSWORD", ""),
+			},
+		},
+		outbox: OutboxConfig{
+			pollInterval:  time.Second * 5,
+			batchSize:     20,
+			lease:         time.Minute,
+			maxAttempts:   8,
+			baseBackoff:   time.Second * 30,
+			maxBackoff:    time.Hour,
+			retention:     time.Hour * 24 * 7,
+			purgeInterval: time.Hour,
+		},
+		timeline: TimelineConfig{
+			celebrityThreshold: env.GetInt("TIMELINE_CELEBRITY_THRESHOLD", 10000),
+			backfillLimit:      200,
+		},
+		idempotencyTTL: time.Hour * 24,
+		cursorSecret:   cursorSecret,
 	}
 	dbPool, err := openDB(cfg.db)
 	if err != nil {
@@ -59,11 +145,27 @@ func main() {
 		log.Fatal(err)
 	}
 
+	authenticator := auth.NewJWTAuthenticator(cfg.auth.token.secret, cfg.auth.token.iss, cfg.auth.token.iss)
+
+	mail, err := newMailer(cfg.mail)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dummyPasswordHash, err := password.Hash("not the password of any user", cfg.auth.password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	app := &application{
-		config: cfg,
-		models: dbModels,
-		logger: logger.Sugar(),
+		config:            cfg,
+		models:            dbModels,
+		logger:            logger.Sugar(),
+		authenticator:     authenticator,
+		mailer:            mail,
+		dummyPasswordHash: string(dummyPasswordHash),
 	}
+	app.oidcProviders = app.newOIDCProviders()
 
 	err = app.run()
 	if err != nil {
@@ -71,6 +173,59 @@ func main() {
 	}
 }
 
+// secretFromEnv reads the signing secret in key. Only development falls back
+// to a placeholder; anywhere else that would let anyone mint valid tokens, so
+// an unset secret is an error.
+func secretFromEnv(environment, key string) (string, error) {
+	if secret := env.GetString(key, ""); secret != "" {
+		return secret, nil
+	}
+	if environment == "development" {
+		return "example", nil
+	}
+	return "", fmt.Errorf("%s must be set when ENV is %q", key, environment)
+}
+
+// argon2ParamsFromEnv reads the password hashing cost from ARGON2_MEMORY_KIB,
+// ARGON2_ITERATIONS and ARGON2_PARALLELISM, refusing values that are not
+// numbers or out of range.
+func argon2ParamsFromEnv() (password.Params, error) {
+	memory, err := env.LookupInt("ARGON2_MEMORY_KIB", int(password.DefaultParams.Memory))
+	if err != nil {
+		return password.Params{}, err
+	}
+	iterations, err := env.LookupInt("ARGON2_ITERATIONS", int(password.DefaultParams.Iterations))
+	if err != nil {
+		return password.Params{}, err
+	}
+	parallelism, err := env.LookupInt("ARGON2_PARALLELISM", int(password.DefaultParams.Parallelism))
+	if err != nil {
+		return password.Params{}, err
+	}
+	return password.NewParams(memory, iterations, parallelism)
+}
+
+// oidcProvidersFromEnv reads the providers named in OIDC_PROVIDERS (comma
+// separated), each configured by OIDC_<NAME>_ISSUER, _CLIENT_ID,
+// _CLIENT_SECRET and optionally _SCOPES (space separated).
+func oidcProvidersFromEnv() map[string]oidc.Config {
+	providers := make(map[string]oidc.Config)
+	for _, name := range strings.Split(env.GetString("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers[name] = oidc.Config{
+			Issuer:       env.GetString(prefix+"ISSUER", ""),
+			ClientID:     env.GetString(prefix+"CLIENT_ID", ""),
+			ClientSecret: env.GetString(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(env.GetString(prefix+"SCOPES", "")),
+		}
+	}
+	return providers
+}
+
 func openDB(config DBConfig) (*pgxpool.Pool, error) {
 	cfg, err := pgxpool.ParseConfig(config.dsn)
 	if err != nil {
@@ -90,3 +245,21 @@ func openDB(config DBConfig) (*pgxpool.Pool, error) {
 
 	return pool, nil
 }
+
+func newMailer(config MailConfig) (mailer.Mailer, error) {
+	switch config.backend {
+	case "smtp":
+		return mailer.NewSMTPMailer(config.smtp.host, config.smtp.port, config.smtp.username, config.smtp.password, config.fromEmail), nil
+	case "log":
+		if config.logFile == "" {
+			return mailer.NewLogMailer(os.Stdout, config.fromEmail), nil
+		}
+		f, err := os.OpenFile(config.logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return mailer.NewLogMailer(f, config.fromEmail), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer backend %q", config.backend)
+	}
+}
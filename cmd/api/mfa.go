@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"social/internal/auth"
+	"social/internal/models"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// totpEnrolmentResponse holds what an authenticator app needs to enrol
+// swagger:model totpEnrolmentResponse
+type totpEnrolmentResponse struct {
+	// Base32 encoded shared secret, for manual entry
+	Secret string `json:"secret"`
+	// otpauth:// URI, usually rendered as a QR code
+	URI string `json:"uri"`
+}
+
+// recoveryCodesResponse holds one-time recovery codes, shown only once
+// swagger:model recoveryCodesResponse
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type totpCodePayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type loginMFAPayload struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code,omitempty,max=20"`
+}
+
+// enrolTOTPHandler godoc
+//
+//	@Summary		Start two-factor enrolment
+//	@Description	Generates a TOTP secret for the authenticated user. It only takes effect once confirmed with a first code.
+//	@Tags			MFA
+//	@Produce		json
+//	@Success		200	{object}	DataResponseTOTPEnrolment
+//	@Failure		401	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mfa/totp [post]
+func (app *application) enrolTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := getAuthUserFromContext(r)
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.models.MFA.EnrolTOTP(r.Context(), user.ID, secret); err != nil {
+		switch {
+		case errors.Is(err, models.ErrTOTPAlreadyEnabled):
+			app.errorConflict(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	response := totpEnrolmentResponse{
+		Secret: secret,
+		URI:    auth.TOTPURI(app.config.auth.totpIssuer, user.Email, secret),
+	}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// confirmTOTPHandler godoc
+//
+//	@Summary		Confirm two-factor enrolment
+//	@Description	Enables two-factor authentication with a first code from the authenticator app and returns ten one-time recovery codes
+//	@Tags			MFA
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		totpCodePayload	true	"TOTP code"
+//	@Success		200		{object}	DataResponseRecoveryCodes
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		409		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mfa/totp/confirm [post]
+func (app *application) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var form totpCodePayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	user := getAuthUserFromContext(r)
+	ctx := r.Context()
+
+	totp, err := app.models.MFA.GetTOTP(ctx, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrTOTPNotEnrolled):
+			app.errorNotFound(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+	if totp.Enabled() {
+		app.errorConflict(w, r, models.ErrTOTPAlreadyEnabled)
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, form.Code, time.Now())
+	if !ok {
+		app.errorBadRequest(w, r, errInvalidTOTPCode)
+		return
+	}
+
+	codes, err := app.models.MFA.ConfirmTOTP(ctx, user.ID, step)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrTOTPAlreadyEnabled):
+			app.errorConflict(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes}); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// disableTOTPHandler godoc
+//
+//	@Summary		Disable two-factor authentication
+//	@Description	Removes the TOTP enrolment and recovery codes of the authenticated user, given a current code
+//	@Tags			MFA
+//	@Accept			json
+//	@Param			request	body	totpCodePayload	true	"TOTP code"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mfa/totp [delete]
+func (app *application) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var form totpCodePayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	user := getAuthUserFromContext(r)
+	ctx := r.Context()
+
+	totp, err := app.models.MFA.GetTOTP(ctx, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrTOTPNotEnrolled):
+			app.errorNotFound(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.verifyTOTPCode(ctx, totp, form.Code); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := app.models.MFA.DisableTOTP(ctx, user.ID); err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// loginMFAHandler godoc
+//
+//	@Summary		Complete a two-factor login
+//	@Description	Exchanges the mfa_token returned by /auth/login plus a TOTP or recovery code for an access token and a refresh token
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		loginMFAPayload	true	"Second factor payload"
+//	@Success		200		{object}	DataResponseToken
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		429		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/login/mfa [post]
+func (app *application) loginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var form loginMFAPayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	userID, err := app.parseMFAToken(form.MFAToken)
+	if err != nil {
+		app.errorUnauthorized(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user, err := app.models.Users.Get(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			app.errorUnauthorized(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	ip := clientIP(r)
+	retryAfter, err := app.loginRetryAfter(ctx, user.Email, ip)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+	if retryAfter > 0 {
+		app.errorTooManyRequests(w, r, "too many failed login attempts", retryAfter)
+		return
+	}
+
+	totp, err := app.models.MFA.GetTOTP(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrTOTPNotEnrolled):
+			app.errorUnauthorized(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+	if !totp.Enabled() {
+		app.errorUnauthorized(w, r, models.ErrTOTPNotEnrolled)
+		return
+	}
+
+	if form.Code != "" {
+		err = app.verifyTOTPCode(ctx, totp, form.Code)
+	} else {
+		err = app.models.MFA.UseRecoveryCode(ctx, userID, form.RecoveryCode)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errInvalidTOTPCode),
+			errors.Is(err, models.ErrTOTPCodeReused),
+			errors.Is(err, models.ErrRecoveryCodeInvalid):
+			if err := app.recordLoginFailure(ctx, user.Email, ip, user); err != nil {
+				app.errorServerError(w, r, err)
+				return
+			}
+			app.errorUnauthorized(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.clearLoginFailures(ctx, user.Email); err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	tokens, err := app.issueTokens(r, userID)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+var errInvalidTOTPCode = errors.New("invalid two-factor code")
+
+// verifyTOTPCode checks code against the enrolment and burns its time step so
+// the same code cannot be replayed.
+func (app *application) verifyTOTPCode(ctx context.Context, totp *models.TOTP, code string) error {
+	step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return errInvalidTOTPCode
+	}
+	return app.models.MFA.UseTOTPStep(ctx, totp.UserID, step)
+}
+
+func (app *application) parseMFAToken(token string) (uuid.UUID, error) {
+	jwtToken, err := app.authenticator.ValidateToken(token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	claims, _ := jwtToken.Claims.(jwt.MapClaims)
+	if typ, _ := claims["typ"].(string); typ != mfaTokenType {
+		return uuid.Nil, errors.New("token is not an mfa token")
+	}
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(subject)
+}
@@ -4,22 +4,203 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 	"social/internal/models"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
 type postKey string
 type userKey string
+type sessionKey string
+type patKey string
 
 const (
-	postCtxKey postKey = "post"
-	userCTXKey userKey = "user"
+	postCtxKey     postKey    = "post"
+	userCTXKey     userKey    = "user"
+	authUserCtxKey userKey    = "authUser"
+	sessionCtxKey  sessionKey = "session"
+	patCtxKey      patKey     = "personalAccessToken"
 )
 
+func (app *application) authTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			app.errorUnauthorized(w, r, errors.New("authorization header is missing"))
+			return
+		}
+
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			app.errorUnauthorized(w, r, errors.New("authorization header is malformed"))
+			return
+		}
+
+		ctx := r.Context()
+		var userID uuid.UUID
+
+		if token := parts[1]; strings.HasPrefix(token, models.PersonalAccessTokenPrefix) {
+			pat, err := app.models.PersonalAccessTokens.GetByToken(ctx, token)
+			if err != nil {
+				switch {
+				case errors.Is(err, models.ErrPersonalAccessTokenInvalid):
+					app.errorUnauthorized(w, r, err)
+					return
+				default:
+					app.errorServerError(w, r, err)
+					return
+				}
+			}
+			userID = pat.UserID
+			ctx = context.WithValue(ctx, patCtxKey, pat)
+		} else {
+			var sessionID uuid.UUID
+			var err error
+			userID, sessionID, err = app.parseAccessToken(token)
+			if err != nil {
+				app.errorUnauthorized(w, r, err)
+				return
+			}
+			if err := app.models.Sessions.Touch(ctx, sessionID, userID); err != nil {
+				switch {
+				case errors.Is(err, models.ErrSessionRevoked):
+					app.errorUnauthorized(w, r, err)
+					return
+				default:
+					app.errorServerError(w, r, err)
+					return
+				}
+			}
+			ctx = context.WithValue(ctx, sessionCtxKey, sessionID)
+		}
+
+		user, err := app.models.Users.Get(ctx, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				app.errorUnauthorized(w, r, err)
+				return
+			default:
+				app.errorServerError(w, r, err)
+				return
+			}
+		}
+
+		ctx = context.WithValue(ctx, authUserCtxKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// parseAccessToken validates a JWT access token and returns the user and
+// session it was issued for.
+func (app *application) parseAccessToken(token string) (uuid.UUID, uuid.UUID, error) {
+	jwtToken, err := app.authenticator.ValidateToken(token)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	claims, _ := jwtToken.Claims.(jwt.MapClaims)
+	if typ, _ := claims["typ"].(string); typ != accessTokenType {
+		return uuid.Nil, uuid.Nil, errors.New("token is not an access token")
+	}
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	sid, _ := claims["sid"].(string)
+	sessionID, err := uuid.Parse(sid)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return userID, sessionID, nil
+}
+
+// requireScope rejects requests made with a personal access token that was
+// not granted scope. Requests authenticated through a login session have
+// every scope.
+func (app *application) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pat := getPersonalAccessTokenFromContext(r); pat != nil && !pat.HasScope(scope) {
+				app.errorForbidden(w, r, fmt.Errorf("token is missing the %q scope", scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireOwnershipOrRole lets the request through if the authenticated user
+// wrote the post loaded by postsContextMiddleware, or if their role is at
+// least as high as roleName.
+func (app *application) requireOwnershipOrRole(roleName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getAuthUserFromContext(r)
+			post := getPostFromContext(r)
+
+			if post.UserID == user.ID {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, err := app.checkRolePrecedence(r.Context(), user, roleName)
+			if err != nil {
+				app.errorServerError(w, r, err)
+				return
+			}
+			if !allowed {
+				app.errorForbidden(w, r, fmt.Errorf("post belongs to another user and requires the %s role", roleName))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (app *application) checkRolePrecedence(ctx context.Context, user *models.User, roleName string) (bool, error) {
+	role, err := app.models.Roles.GetByName(ctx, roleName)
+	if err != nil {
+		return false, err
+	}
+	return user.Role != nil && user.Role.Level >= role.Level, nil
+}
+
+// requireSession rejects requests made with a personal access token, keeping
+// account management to interactive logins.
+func (app *application) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if getPersonalAccessTokenFromContext(r) != nil {
+			app.errorForbidden(w, r, errors.New("personal access tokens cannot manage the account"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func getAuthUserFromContext(r *http.Request) *models.User {
+	return r.Context().Value(authUserCtxKey).(*models.User)
+}
+
+func getSessionIDFromContext(r *http.Request) uuid.UUID {
+	return r.Context().Value(sessionCtxKey).(uuid.UUID)
+}
+
+func getPersonalAccessTokenFromContext(r *http.Request) *models.PersonalAccessToken {
+	pat, _ := r.Context().Value(patCtxKey).(*models.PersonalAccessToken)
+	return pat
+}
+
 func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		postID, err := uuid.Parse(chi.URLParam(r, "postID"))
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"social/internal/auth/oidc"
+	"social/internal/models"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// oidcPasswordPlaceholder is stored as the password of accounts created
+// through an identity provider. It is no valid hash, so password login fails
+// until the user sets one with a password reset.
+const oidcPasswordPlaceholder = "!oidc"
+
+// oidcStateCookie carries the state of a sign-in to the browser that started
+// it, so a callback with a state issued to someone else is refused.
+const oidcStateCookie = "oidc_state"
+
+// startOIDCHandler godoc
+//
+//	@Summary		Start signing in with an identity provider
+//	@Description	Redirects to the provider's login page using the authorization code flow with PKCE. The state is also set in a short-lived cookie that the callback must present.
+//	@Tags			Auth
+//	@Param			provider	path	string	true	"Provider name"
+//	@Success		302			"Redirect to the provider"
+//	@Failure		404			{object}	ErrorResponse
+//	@Failure		500			{object}	ErrorResponse
+//	@Router			/auth/oidc/{provider}/start [get]
+func (app *application) startOIDCHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	provider, ok := app.oidcProviders[name]
+	if !ok {
+		app.errorNotFound(w, r, errors.New("unknown identity provider "+name))
+		return
+	}
+
+	state, err := models.NewOIDCState(name, app.config.auth.oidc.stateExp)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+	if err := app.models.OIDCStates.Create(r.Context(), state); err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	redirectURL, err := provider.AuthCodeURL(r.Context(), state.Plaintext, state.Nonce, state.CodeVerifier)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+	app.setOIDCStateCookie(w, name, state.Plaintext, int(app.config.auth.oidc.stateExp/time.Second))
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+// oidcCallbackHandler godoc
+//
+//	@Summary		Finish signing in with an identity provider
+//	@Description	Exchanges the authorization code for the provider's identity and logs in the linked user. Unknown identities are linked to the account with the same email, or get a new account, when the provider has verified the email; such accounts are activated right away.
+//	@Tags			Auth
+//	@Produce		json
+//	@Param			provider	path		string	true	"Provider name"
+//	@Param			code		query		string	true	"Authorization code"
+//	@Param			state		query		string	true	"State returned by the provider"
+//	@Success		200			{object}	DataResponseToken
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		401			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Failure		409			{object}	ErrorResponse
+//	@Failure		500			{object}	ErrorResponse
+//	@Router			/auth/oidc/{provider}/callback [get]
+func (app *application) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	provider, ok := app.oidcProviders[name]
+	if !ok {
+		app.errorNotFound(w, r, errors.New("unknown identity provider "+name))
+		return
+	}
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		app.errorUnauthorized(w, r, errors.New("identity provider error: "+providerErr))
+		return
+	}
+	code, stateParam := query.Get("code"), query.Get("state")
+	if code == "" || stateParam == "" {
+		app.errorBadRequest(w, r, errors.New("code and state are required"))
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(stateParam)) != 1 {
+		app.errorBadRequest(w, r, models.ErrOIDCStateInvalid)
+		return
+	}
+	app.setOIDCStateCookie(w, name, "", -1)
+
+	ctx := r.Context()
+	state, err := app.models.OIDCStates.Consume(ctx, name, stateParam)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrOIDCStateInvalid):
+			app.errorBadRequest(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	claims, err := provider.Exchange(ctx, code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrInvalidIDToken):
+			app.errorUnauthorized(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	user, err := app.userForIdentity(r, name, claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, errOIDCEmailUnverified):
+			app.errorForbidden(w, r, err)
+			return
+		case errors.Is(err, models.ErrIdentityAlreadyLinked), errors.Is(err, models.ErrDuplicateEmail):
+			app.errorConflict(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if !user.IsActivated {
+		app.errorUnauthorized(w, r, errors.New("user is not activated"))
+		return
+	}
+
+	tokens, err := app.loginTokens(r, user.ID)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// setOIDCStateCookie stores state for the callback of provider, or deletes
+// the cookie when maxAge is negative. SameSite=Lax lets it through on the
+// provider's top-level redirect back to the API.
+func (app *application) setOIDCStateCookie(w http.ResponseWriter, provider, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/v1/auth/oidc/" + provider,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   app.config.env != "development",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+var errOIDCEmailUnverified = errors.New("identity provider has not verified the email address")
+
+// userForIdentity returns the user linked to the provider identity, linking or
+// creating one on first sign-in. Only an email verified by the provider is
+// trusted to match or create an account.
+func (app *application) userForIdentity(r *http.Request, provider string, claims *oidc.Claims) (*models.User, error) {
+	ctx := r.Context()
+
+	userID, err := app.models.Identities.GetUserID(ctx, provider, claims.Subject)
+	if err == nil {
+		return app.models.Users.Get(ctx, userID)
+	}
+	if !errors.Is(err, models.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errOIDCEmailUnverified
+	}
+
+	identityID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	identity := &models.Identity{
+		ID:       identityID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	user, err := app.models.Users.GetByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		identity.UserID = user.ID
+		if err := app.models.Identities.Link(ctx, identity, []byte(oidcPasswordPlaceholder)); err != nil {
+			return nil, err
+		}
+		if !user.IsActivated {
+			user.IsActivated = true
+			user.Password = oidcPasswordPlaceholder
+		}
+		return user, nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, err
+	}
+
+	base := oidcUsername(claims)
+	username := base
+	for attempt := 0; ; attempt++ {
+		userID, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		user = &models.User{
+			ID:          userID,
+			Username:    username,
+			Email:       claims.Email,
+			Password:    oidcPasswordPlaceholder,
+			IsActivated: true,
+		}
+		err = app.models.Identities.CreateUser(ctx, user, identity)
+		if errors.Is(err, models.ErrDuplicateUsername) && attempt < 3 {
+			username, err = usernameWithSuffix(base)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+}
+
+// oidcUsername picks a username from the provider's claims that fits the
+// signup rules: 3 to 20 characters.
+func oidcUsername(claims *oidc.Claims) string {
+	candidate := claims.PreferredUsername
+	if candidate == "" {
+		candidate, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	var b strings.Builder
+	for _, c := range strings.ToLower(candidate) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' || c == '.' || c == '-' {
+			b.WriteRune(c)
+		}
+	}
+	username := b.String()
+	if len(username) > 20 {
+		username = username[:20]
+	}
+	if len(username) < 3 {
+		username = "user"
+	}
+	return username
+}
+
+func usernameWithSuffix(base string) (string, error) {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	if len(base) > 15 {
+		base = base[:15]
+	}
+	return base + "_" + hex.EncodeToString(suffix), nil
+}
+
+// newOIDCProviders builds the configured identity providers, with callbacks
+// pointing back at this API.
+func (app *application) newOIDCProviders() map[string]*oidc.Provider {
+	providers := make(map[string]*oidc.Provider, len(app.config.auth.oidc.providers))
+	for name, cfg := range app.config.auth.oidc.providers {
+		cfg.RedirectURL = app.externalURL("/v1/auth/oidc/" + name + "/callback")
+		providers[name] = oidc.NewProvider(cfg, nil)
+	}
+	return providers
+}
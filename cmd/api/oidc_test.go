@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"social/internal/auth/oidc"
+	"social/internal/auth/oidc/oidctest"
+	"social/internal/models"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcTest runs the API in front of a stub identity provider registered as
+// "stub".
+type oidcTest struct {
+	app  *application
+	stub *oidctest.Server
+	api  *httptest.Server
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	t.Helper()
+
+	app := newTestApplication(t)
+	stub := oidctest.NewServer(t)
+	api := httptest.NewServer(app.router())
+	t.Cleanup(api.Close)
+
+	app.config.apiURL = api.URL
+	app.config.auth.oidc.providers = map[string]oidc.Config{"stub": stub.Config("")}
+	app.oidcProviders = app.newOIDCProviders()
+	return &oidcTest{app: app, stub: stub, api: api}
+}
+
+// browser returns a client with its own cookie jar that stops at redirects,
+// so each hop of the flow can be inspected.
+func (o *oidcTest) browser(t *testing.T) *http.Client {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// authorize starts a sign-in in browser and returns the callback URL the
+// provider redirects back to.
+func (o *oidcTest) authorize(t *testing.T, browser *http.Client) *url.URL {
+	t.Helper()
+
+	location := o.get(t, browser, o.api.URL+"/v1/auth/oidc/stub/start", http.StatusFound).Header.Get("Location")
+	location = o.get(t, browser, location, http.StatusFound).Header.Get("Location")
+	callback, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback
+}
+
+func (o *oidcTest) get(t *testing.T, client *http.Client, target string, wantStatus int) *http.Response {
+	t.Helper()
+
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != wantStatus {
+		var problem ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&problem)
+		t.Fatalf("GET %s: got status %d (%s), want %d", target, resp.StatusCode, problem.Detail, wantStatus)
+	}
+	return resp
+}
+
+func (o *oidcTest) user(t *testing.T, email string) *models.User {
+	t.Helper()
+
+	user, err := o.app.models.Users.GetByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatalf("user %s: %v", email, err)
+	}
+	return user
+}
+
+func TestOIDCSignInCreatesActivatedUser(t *testing.T) {
+	o := newOIDCTest(t)
+	o.stub.Identity = oidctest.Identity{Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true, PreferredUsername: "alice"}
+
+	browser := o.browser(t)
+	callback := o.authorize(t, browser)
+	resp := o.get(t, browser, callback.String(), http.StatusOK)
+
+	var body DataResponseToken
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.Token == "" || body.Data.RefreshToken == "" {
+		t.Fatalf("expected access and refresh tokens, got %+v", body.Data)
+	}
+
+	user := o.user(t, "alice@example.com")
+	if !user.IsActivated || user.Username != "alice" {
+		t.Fatalf("expected activated user alice, got %+v", user)
+	}
+}
+
+func TestOIDCSignInLinksVerifiedEmail(t *testing.T) {
+	o := newOIDCTest(t)
+	existing := o.app.models.Users.(*fakeUsers).add(models.User{Username: "bob", Email: "bob@example.com"})
+	o.stub.Identity = oidctest.Identity{Subject: "bob-sub", Email: "bob@example.com", EmailVerified: true}
+
+	browser := o.browser(t)
+	o.get(t, browser, o.authorize(t, browser).String(), http.StatusOK)
+
+	user := o.user(t, "bob@example.com")
+	if user.ID != existing.ID || !user.IsActivated {
+		t.Fatalf("expected the existing user to be linked and activated, got %+v", user)
+	}
+}
+
+func TestOIDCSignInClaimsUnactivatedAccount(t *testing.T) {
+	o := newOIDCTest(t)
+	// an attacker signed up with the victim's address and a password of their own
+	hash, err := o.app.hashPassword("attacker's password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	squatted := o.app.models.Users.(*fakeUsers).add(models.User{Username: "squatter", Email: "dave@example.com", Password: string(hash)})
+	o.stub.Identity = oidctest.Identity{Subject: "dave-sub", Email: "dave@example.com", EmailVerified: true}
+
+	browser := o.browser(t)
+	o.get(t, browser, o.authorize(t, browser).String(), http.StatusOK)
+
+	user := o.user(t, "dave@example.com")
+	if !user.IsActivated {
+		t.Fatal("the account was not activated by the verified sign-in")
+	}
+	if err := checkPassword(user.Password, "attacker's password"); err == nil {
+		t.Fatal("the password chosen before the address was verified still works")
+	}
+	if revoked := o.app.models.Identities.(*fakeIdentities).revoked; len(revoked) != 1 || revoked[0] != squatted.ID {
+		t.Fatalf("expected the sessions of %s to be revoked, got %v", squatted.ID, revoked)
+	}
+}
+
+func TestOIDCSignInKeepsActivatedAccountPassword(t *testing.T) {
+	o := newOIDCTest(t)
+	hash, err := o.app.hashPassword("erin's own password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.app.models.Users.(*fakeUsers).add(models.User{Username: "erin", Email: "erin@example.com", Password: string(hash), IsActivated: true})
+	o.stub.Identity = oidctest.Identity{Subject: "erin-sub", Email: "erin@example.com", EmailVerified: true}
+
+	browser := o.browser(t)
+	o.get(t, browser, o.authorize(t, browser).String(), http.StatusOK)
+
+	if err := checkPassword(o.user(t, "erin@example.com").Password, "erin's own password"); err != nil {
+		t.Fatalf("linking an activated account changed its password: %v", err)
+	}
+	if revoked := o.app.models.Identities.(*fakeIdentities).revoked; len(revoked) != 0 {
+		t.Fatalf("linking an activated account revoked sessions of %v", revoked)
+	}
+}
+
+func TestOIDCSignUpRaceIsAConflict(t *testing.T) {
+	o := newOIDCTest(t)
+	o.stub.Identity = oidctest.Identity{Subject: "frank-sub", Email: "frank@example.com", EmailVerified: true}
+	// another sign-up takes the address between the lookup and the insert
+	o.app.models.Identities.(*fakeIdentities).beforeCreateUser = func(user *models.User) {
+		o.app.models.Users.(*fakeUsers).add(models.User{Username: "frank", Email: user.Email})
+	}
+
+	browser := o.browser(t)
+	o.get(t, browser, o.authorize(t, browser).String(), http.StatusConflict)
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	o := newOIDCTest(t)
+	o.stub.Identity = oidctest.Identity{Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true}
+
+	t.Run("tampered state", func(t *testing.T) {
+		browser := o.browser(t)
+		callback := o.authorize(t, browser)
+		query := callback.Query()
+		query.Set("state", "forged")
+		callback.RawQuery = query.Encode()
+
+		o.get(t, browser, callback.String(), http.StatusBadRequest)
+	})
+
+	t.Run("state issued to another browser", func(t *testing.T) {
+		victim := o.browser(t)
+		attacker := o.browser(t)
+		// the attacker's callback URL replayed in the victim's browser
+		callback := o.authorize(t, attacker)
+
+		o.get(t, victim, callback.String(), http.StatusBadRequest)
+		// the state is still good for the browser that started the flow
+		o.get(t, attacker, callback.String(), http.StatusOK)
+	})
+
+	t.Run("state used twice", func(t *testing.T) {
+		browser := o.browser(t)
+		callback := o.authorize(t, browser)
+		o.get(t, browser, callback.String(), http.StatusOK)
+
+		// the cookie is gone after the first callback; restore it to check
+		// the state itself is single-use
+		browser.Jar.SetCookies(callback, []*http.Cookie{{Name: oidcStateCookie, Value: callback.Query().Get("state"), Path: "/v1/auth/oidc/stub"}})
+		o.get(t, browser, callback.String(), http.StatusBadRequest)
+	})
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	o := newOIDCTest(t)
+	o.stub.Identity = oidctest.Identity{Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true}
+	o.stub.TamperClaims = func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }
+
+	browser := o.browser(t)
+	o.get(t, browser, o.authorize(t, browser).String(), http.StatusUnauthorized)
+
+	if _, err := o.app.models.Users.GetByEmail(context.Background(), "alice@example.com"); err == nil {
+		t.Fatal("a user was created from a token with the wrong nonce")
+	}
+}
+
+func TestOIDCUnverifiedEmailDoesNotActivate(t *testing.T) {
+	o := newOIDCTest(t)
+	o.app.models.Users.(*fakeUsers).add(models.User{Username: "carol", Email: "carol@example.com"})
+	o.stub.Identity = oidctest.Identity{Subject: "carol-sub", Email: "carol@example.com", EmailVerified: false}
+
+	browser := o.browser(t)
+	o.get(t, browser, o.authorize(t, browser).String(), http.StatusForbidden)
+
+	if user := o.user(t, "carol@example.com"); user.IsActivated {
+		t.Fatal("an unverified provider email activated the account")
+	}
+	if _, err := o.app.models.Identities.GetUserID(context.Background(), "stub", "carol-sub"); err == nil {
+		t.Fatal("an unverified provider email was linked to the account")
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"social/internal/mailer"
+	"social/internal/models"
+	"time"
+)
+
+type outboxHandler func(ctx context.Context, payload json.RawMessage) error
+
+// outboxHandlers maps every outbox kind to the function delivering it.
+func (app *application) outboxHandlers() map[string]outboxHandler {
+	return map[string]outboxHandler{
+		models.OutboxKindUserInvited:    app.deliverUserInvited,
+		models.OutboxKindPostCreated:    app.fanOutPost,
+		models.OutboxKindUserFollowed:   app.backfillTimeline,
+		models.OutboxKindUserUnfollowed: app.pruneTimeline,
+
+		models.OutboxKindPasswordResetRequested: app.deliverPasswordReset,
+		models.OutboxKindEmailChangeRequested:   app.deliverEmailChangeConfirm,
+		models.OutboxKindEmailChangeNotice:      app.deliverEmailChangeNotice,
+		models.OutboxKindMagicLinkRequested:     app.deliverMagicLink,
+		models.OutboxKindAccountLocked:          app.deliverAccountLocked,
+	}
+}
+
+// runOutboxDispatcher polls the outbox until ctx is cancelled. Failed
+// deliveries are retried with exponential backoff and moved to the dead
+// state once they run out of attempts, and finished messages are purged
+// after the retention period.
+func (app *application) runOutboxDispatcher(ctx context.Context) {
+	cfg := app.config.outbox
+	handlers := app.outboxHandlers()
+
+	ticker := time.NewTicker(cfg.pollInterval)
+	defer ticker.Stop()
+	purge := time.NewTicker(cfg.purgeInterval)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-purge.C:
+			purged, err := app.models.Outbox.Purge(ctx, time.Now().Add(-cfg.retention))
+			if err != nil {
+				app.logger.Errorw("error purging outbox messages", "error", err)
+			} else if purged > 0 {
+				app.logger.Infow("purged outbox messages", "count", purged)
+			}
+			continue
+		case <-ticker.C:
+		}
+
+		messages, err := app.models.Outbox.Claim(ctx, cfg.batchSize, cfg.lease)
+		if err != nil {
+			app.logger.Errorw("error claiming outbox messages", "error", err)
+			continue
+		}
+
+		for _, message := range messages {
+			app.dispatchOutboxMessage(ctx, handlers, message)
+		}
+	}
+}
+
+func (app *application) dispatchOutboxMessage(ctx context.Context, handlers map[string]outboxHandler, message models.OutboxMessage) {
+	cfg := app.config.outbox
+
+	err := fmt.Errorf("no handler for outbox kind %q", message.Kind)
+	if handler, ok := handlers[message.Kind]; ok {
+		err = handler(ctx, message.Payload)
+	}
+
+	if err == nil {
+		if err := app.models.Outbox.MarkDelivered(ctx, message.ID); err != nil {
+			app.logger.Errorw("error marking outbox message delivered", "id", message.ID, "error", err)
+		}
+		return
+	}
+
+	if message.Attempts >= cfg.maxAttempts {
+		app.logger.Errorw("outbox message dead-lettered", "id", message.ID, "kind", message.Kind, "attempts", message.Attempts, "error", err)
+		if err := app.models.Outbox.DeadLetter(ctx, message.ID, err.Error()); err != nil {
+			app.logger.Errorw("error dead-lettering outbox message", "id", message.ID, "error", err)
+		}
+		return
+	}
+
+	retryAt := time.Now().Add(outboxBackoff(cfg.baseBackoff, cfg.maxBackoff, message.Attempts))
+	app.logger.Warnw("outbox delivery failed, retrying", "id", message.ID, "kind", message.Kind, "attempts", message.Attempts, "retry_at", retryAt, "error", err)
+	if err := app.models.Outbox.Retry(ctx, message.ID, err.Error(), retryAt); err != nil {
+		app.logger.Errorw("error rescheduling outbox message", "id", message.ID, "error", err)
+	}
+}
+
+// outboxBackoff doubles base for every attempt already made, caps it at
+// ceiling and adds up to 10% jitter so failed messages do not retry in
+// lockstep.
+func outboxBackoff(base, ceiling time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < ceiling; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, ceiling)
+	return backoff + rand.N(backoff/10+1)
+}
+
+func (app *application) deliverUserInvited(_ context.Context, payload json.RawMessage) error {
+	var event models.UserInvitedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+
+	data := struct {
+		Username      string
+		ActivationURL string
+		ExpiresAt     time.Time
+	}{
+		Username:      event.Username,
+		ActivationURL: app.frontendURL("/activate?token=" + event.InviteToken.String()),
+		ExpiresAt:     event.ExpiresAt,
+	}
+	return app.mailer.Send(mailer.UserInvitationTemplate, event.Username, event.Email, data)
+}
+
+func (app *application) deliverPasswordReset(_ context.Context, payload json.RawMessage) error {
+	var event models.PasswordResetRequestedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+
+	data := struct {
+		Username  string
+		Token     string
+		ResetURL  string
+		ExpiresAt time.Time
+	}{
+		Username:  event.Username,
+		Token:     event.Token,
+		ResetURL:  app.frontendURL("/password/reset?token=" + url.QueryEscape(event.Token)),
+		ExpiresAt: event.ExpiresAt,
+	}
+	return app.mailer.Send(mailer.PasswordResetTemplate, event.Username, event.Email, data)
+}
+
+func (app *application) deliverEmailChangeConfirm(_ context.Context, payload json.RawMessage) error {
+	var event models.EmailChangeRequestedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+
+	data := struct {
+		Username   string
+		ConfirmURL string
+		ExpiresAt  time.Time
+	}{
+		Username:   event.Username,
+		ConfirmURL: app.frontendURL("/email/confirm?token=" + url.QueryEscape(event.Token)),
+		ExpiresAt:  event.ExpiresAt,
+	}
+	return app.mailer.Send(mailer.EmailChangeConfirmTemplate, event.Username, event.NewEmail, data)
+}
+
+func (app *application) deliverEmailChangeNotice(_ context.Context, payload json.RawMessage) error {
+	var event models.EmailChangeNoticeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+
+	data := struct {
+		Username string
+		NewEmail string
+	}{
+		Username: event.Username,
+		NewEmail: event.NewEmail,
+	}
+	return app.mailer.Send(mailer.EmailChangeNoticeTemplate, event.Username, event.Email, data)
+}
+
+func (app *application) deliverMagicLink(_ context.Context, payload json.RawMessage) error {
+	var event models.MagicLinkRequestedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+
+	data := struct {
+		Username  string
+		LoginURL  string
+		ExpiresAt time.Time
+	}{
+		Username:  event.Username,
+		LoginURL:  app.frontendURL("/magic-link?token=" + url.QueryEscape(event.Token)),
+		ExpiresAt: event.ExpiresAt,
+	}
+	return app.mailer.Send(mailer.MagicLinkTemplate, event.Username, event.Email, data)
+}
+
+func (app *application) deliverAccountLocked(_ context.Context, payload json.RawMessage) error {
+	var event models.AccountLockedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+
+	data := struct {
+		Username    string
+		IP          string
+		LockedUntil time.Time
+		ResetURL    string
+	}{
+		Username:    event.Username,
+		IP:          event.IP,
+		LockedUntil: event.LockedUntil,
+		ResetURL:    app.frontendURL("/password/forgot"),
+	}
+	return app.mailer.Send(mailer.AccountLockedTemplate, event.Username, event.Email, data)
+}
+
+func (app *application) fanOutPost(ctx context.Context, payload json.RawMessage) error {
+	var event models.PostCreatedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+	return app.models.Timelines.FanOut(ctx, event.PostID, app.config.timeline.celebrityThreshold)
+}
+
+func (app *application) backfillTimeline(ctx context.Context, payload json.RawMessage) error {
+	var event models.FollowEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+	cfg := app.config.timeline
+	return app.models.Timelines.Backfill(ctx, event.FollowerID, event.UserID, cfg.backfillLimit, cfg.celebrityThreshold)
+}
+
+func (app *application) pruneTimeline(ctx context.Context, payload json.RawMessage) error {
+	var event models.FollowEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+	return app.models.Timelines.Prune(ctx, event.FollowerID, event.UserID)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"social/internal/auth/password"
+	"social/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type forgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type resetPasswordPayload struct {
+	Token    string `json:"token" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,min=12,max=128"`
+}
+
+// forgotPasswordHandler godoc
+//
+//	@Summary		Request a password reset
+//	@Description	Emails a single-use password reset token to an activated account. The response is the same whether or not the email belongs to an account. Requests are limited per address and per client IP.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		forgotPasswordPayload	true	"Forgot password payload"
+//	@Success		202		{object}	nil
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		429		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/password/forgot [post]
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var form forgotPasswordPayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	retryAfter, err := app.throttleEmailRequest(ctx, "password_reset", form.Email, clientIP(r))
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+	if retryAfter > 0 {
+		app.errorTooManyRequests(w, r, "too many password reset requests", retryAfter)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(ctx, form.Email)
+	switch {
+	case err == nil && user.IsActivated:
+		if _, err := app.models.PasswordResets.Create(ctx, user, app.config.auth.passwordResetExp); err != nil {
+			app.errorServerError(w, r, err)
+			return
+		}
+	case err == nil, errors.Is(err, pgx.ErrNoRows):
+		// answered like a successful request so callers cannot probe for accounts
+	default:
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// resetPasswordHandler godoc
+//
+//	@Summary		Reset a password
+//	@Description	Sets a new password using a reset token and signs the user out of every session
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		resetPasswordPayload	true	"Reset password payload"
+//	@Success		200		{object}	nil
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/auth/password/reset [post]
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var form resetPasswordPayload
+
+	if err := readJSON(w, r, &form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(form); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	user, err := app.models.PasswordResets.GetUser(r.Context(), form.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrPasswordResetInvalid):
+			app.errorBadRequest(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := password.Check("password", form.Password, user.Username, user.Email); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	hashedPassword, err := app.hashPassword(form.Password)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.models.PasswordResets.Reset(r.Context(), form.Token, hashedPassword); err != nil {
+		switch {
+		case errors.Is(err, models.ErrPasswordResetInvalid):
+			app.errorBadRequest(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
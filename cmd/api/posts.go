@@ -1,10 +1,14 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 	"social/internal/models"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // postPayload represents the payload to create a post
@@ -28,10 +32,14 @@ type postPayload struct {
 //	@Tags			Posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		postPayload	true	"Post payload"
-//	@Success		201		{object}	DataResponsePost
-//	@Failure		400		{object}	ErrorResponse
-//	@Failure		500		{object}	ErrorResponse
+//	@Param			request			body		postPayload	true	"Post payload"
+//	@Param			Idempotency-Key	header		string		false	"Makes retries of this request return the first response"
+//	@Success		201				{object}	DataResponsePost
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		401				{object}	ErrorResponse
+//	@Failure		403				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Security		ApiKeyAuth
 //	@Router			/posts [post]
 func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request) {
 	var payload postPayload
@@ -51,15 +59,14 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		app.errorServerError(w, r, err)
 		return
 	}
-	// todo: get userID from request context
-	userID := uuid.MustParse("b58e1f73-028f-4c17-b8ac-8a3b416c69fd")
+	user := getAuthUserFromContext(r)
 
 	post := &models.Post{
 		ID:      postID,
 		Title:   payload.Title,
 		Content: payload.Content,
 		Tags:    payload.Tags,
-		UserID:  userID,
+		UserID:  user.ID,
 	}
 	err = app.models.Posts.Create(r.Context(), post)
 	if err != nil {
@@ -67,6 +74,7 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	w.Header().Set("ETag", postETag(post))
 	err = app.jsonResponse(w, http.StatusCreated, post)
 	if err != nil {
 		app.errorServerError(w, r, err)
@@ -81,8 +89,12 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 //	@Produce		json
 //	@Param			postID	path		string	true	"Post ID (UUID)"
 //	@Success		200		{object}	DataResponsePost
+//	@Header			200		{string}	ETag	"Version of the post, for If-Match"
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
 //	@Failure		404		{object}	ErrorResponse
 //	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
 //	@Router			/posts/{postID} [get]
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromContext(r)
@@ -93,6 +105,7 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	post.Comments = comments
 
+	w.Header().Set("ETag", postETag(post))
 	err = app.jsonResponse(w, http.StatusOK, post)
 	if err != nil {
 		app.errorServerError(w, r, err)
@@ -102,21 +115,42 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 // deletePostHandler godoc
 //
 //	@Summary		Delete a post
-//	@Description	Deletes a post by ID
+//	@Description	Deletes a post by ID, only if it is still at the version named by the ETag in If-Match.
 //	@Tags			Posts
-//	@Param			postID	path	string	true	"Post ID (UUID)"
-//	@Success		204		"No Content"
-//	@Failure		404		{object}	ErrorResponse
-//	@Failure		500		{object}	ErrorResponse
+//	@Param			postID		path	string	true	"Post ID (UUID)"
+//	@Param			If-Match	header	string	true	"ETag of the version being deleted"
+//	@Success		204			"No Content"
+//	@Failure		401			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Failure		412			{object}	ErrorResponse
+//	@Failure		428			{object}	ErrorResponse
+//	@Failure		500			{object}	ErrorResponse
+//	@Security		ApiKeyAuth
 //	@Router			/posts/{postID} [delete]
 func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromContext(r)
-	err := app.models.Posts.Delete(r.Context(), post.ID)
-	if err != nil {
-		app.errorServerError(w, r, err)
+	if r.Header.Get("If-Match") == "" {
+		app.errorPreconditionRequired(w, r, "If-Match")
+		return
+	}
+	if !ifMatchPost(r, post) {
+		app.errorEditConflict(w, r, post)
 		return
 	}
 
+	err := app.models.Posts.Delete(r.Context(), post.ID, post.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEditConflict):
+			app.postEditConflict(w, r, post.ID)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
 	err = app.jsonResponse(w, http.StatusNoContent, nil)
 	if err != nil {
 		app.errorServerError(w, r, err)
@@ -140,24 +174,36 @@ type updatePostPayload struct {
 // updatePostHandler godoc
 //
 //	@Summary		Update a post
-//	@Description	Updates a post by ID
+//	@Description	Updates a post by ID, only if it is still at the version named by the ETag in If-Match; a post changed in the meantime is returned with the error.
 //	@Tags			Posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			postID	path		string				true	"Post ID (UUID)"
-//	@Param			request	body		updatePostPayload	true	"Update payload"
-//	@Success		200		{object}	DataResponsePost
-//	@Failure		400		{object}	ErrorResponse
-//	@Failure		404		{object}	ErrorResponse
-//	@Failure		500		{object}	ErrorResponse
+//	@Param			postID		path		string				true	"Post ID (UUID)"
+//	@Param			If-Match	header		string				true	"ETag of the version being updated"
+//	@Param			request		body		updatePostPayload	true	"Update payload"
+//	@Success		200			{object}	DataResponsePost
+//	@Header			200			{string}	ETag	"New version of the post"
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		401			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Failure		412			{object}	ErrorResponse
+//	@Failure		428			{object}	ErrorResponse
+//	@Failure		500			{object}	ErrorResponse
+//	@Security		ApiKeyAuth
 //	@Router			/posts/{postID} [patch]
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromContext(r)
+	if r.Header.Get("If-Match") == "" {
+		app.errorPreconditionRequired(w, r, "If-Match")
+		return
+	}
 
 	var payload updatePostPayload
 	err := readJSON(w, r, &payload)
 	if err != nil {
 		app.errorBadRequest(w, r, err)
+		return
 	}
 
 	err = Validate.Struct(payload)
@@ -166,6 +212,11 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if !ifMatchPost(r, post) {
+		app.errorEditConflict(w, r, post)
+		return
+	}
+
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
@@ -178,12 +229,56 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 
 	err = app.models.Posts.Update(r.Context(), post)
 	if err != nil {
-		app.errorServerError(w, r, err)
-		return
+		switch {
+		case errors.Is(err, models.ErrEditConflict):
+			app.postEditConflict(w, r, post.ID)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
 	}
 
+	w.Header().Set("ETag", postETag(post))
 	err = app.jsonResponse(w, http.StatusOK, post)
 	if err != nil {
 		app.errorServerError(w, r, err)
 	}
 }
+
+// postETag is the entity tag of a post: its version, which every edit bumps.
+func postETag(post *models.Post) string {
+	return `"` + strconv.Itoa(post.Version) + `"`
+}
+
+// ifMatchPost reports whether the If-Match header names the current version
+// of post.
+func ifMatchPost(r *http.Request, post *models.Post) bool {
+	etag := postETag(post)
+	for _, value := range r.Header.Values("If-Match") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// postEditConflict answers a write that passed If-Match but lost a race with
+// another edit before it was saved.
+func (app *application) postEditConflict(w http.ResponseWriter, r *http.Request, postID uuid.UUID) {
+	current, err := app.models.Posts.GetByID(r.Context(), postID)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			app.errorNotFound(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	app.errorEditConflict(w, r, current)
+}
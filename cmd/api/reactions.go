@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"social/internal/models"
+)
+
+// reactToPostHandler godoc
+//
+//	@Summary		React to a post
+//	@Description	Adds the user's reaction to the post. Reacting again is a no-op. Reactions count towards the post's rank in the top feed.
+//	@Tags			Posts
+//	@Param			postID	path	string	true	"Post ID (UUID)"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reaction [put]
+func (app *application) reactToPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+	user := getAuthUserFromContext(r)
+
+	err := app.models.Reactions.React(r.Context(), post.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrForeignKeyViolation):
+			app.errorBadRequest(w, r, errors.New("post does not exist"))
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// unreactToPostHandler godoc
+//
+//	@Summary		Remove a reaction from a post
+//	@Description	Removes the user's reaction to the post, if any
+//	@Tags			Posts
+//	@Param			postID	path	string	true	"Post ID (UUID)"
+//	@Success		204		"No Content"
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reaction [delete]
+func (app *application) unreactToPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+	user := getAuthUserFromContext(r)
+
+	if err := app.models.Reactions.Unreact(r.Context(), post.ID, user.ID); err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"social/internal/models"
+)
+
+// searchHandler godoc
+//
+//	@Summary		Search posts, comments or users
+//	@Description	Full-text search, best matches first. q accepts web search syntax: "quoted phrases", OR, and -word to exclude. The snippet is HTML: its text is escaped and matched words are wrapped in <mark> tags. Personal access tokens need the posts:read scope, and users:read as well to search users.
+//	@Tags			Search
+//	@Produce		json
+//	@Param			q		query		string	true	"Search terms"
+//	@Param			type	query		string	false	"What to search"		Enums(posts,comments,users)
+//	@Param			limit	query		int		false	"Items per page"		minimum(1)	maximum(20)
+//	@Param			offset	query		int		false	"Offset for pagination"	minimum(0)
+//	@Success		200		{object}	DataResponseSearch
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := models.SearchQuery{
+		Type:  models.SearchTypePosts,
+		Limit: 20,
+	}.Parse(r)
+	if err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	search := app.models.Search.Posts
+	switch q.Type {
+	case models.SearchTypeComments:
+		search = app.models.Search.Comments
+	case models.SearchTypeUsers:
+		// posts:read is checked by the route; users need their own scope
+		if pat := getPersonalAccessTokenFromContext(r); pat != nil && !pat.HasScope(models.ScopeUsersRead) {
+			app.errorForbidden(w, r, fmt.Errorf("token is missing the %q scope", models.ScopeUsersRead))
+			return
+		}
+		search = app.models.Search.Users
+	}
+
+	results, err := search(r.Context(), q)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, results); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"social/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// getSessionsHandler godoc
+//
+//	@Summary		List active sessions
+//	@Description	Lists the devices the authenticated user is signed in on
+//	@Tags			Sessions
+//	@Produce		json
+//	@Success		200	{object}	DataResponseSessions
+//	@Failure		401	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/sessions [get]
+func (app *application) getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getAuthUserFromContext(r)
+	currentSessionID := getSessionIDFromContext(r)
+
+	sessions, err := app.models.Sessions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, sessions); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// deleteSessionHandler godoc
+//
+//	@Summary		Sign out a session
+//	@Description	Revokes one of the authenticated user's sessions and its refresh tokens
+//	@Tags			Sessions
+//	@Param			sessionID	path	string	true	"Session ID (UUID)"
+//	@Success		204			"No Content"
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		401			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Failure		500			{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/sessions/{sessionID} [delete]
+func (app *application) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(chi.URLParam(r, "sessionID"))
+	if err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+	user := getAuthUserFromContext(r)
+
+	err = app.models.Sessions.Revoke(r.Context(), user.ID, sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrSessionNotFound):
+			app.errorNotFound(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// deleteOtherSessionsHandler godoc
+//
+//	@Summary		Sign out all other sessions
+//	@Description	Revokes every session of the authenticated user except the one making the request
+//	@Tags			Sessions
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/sessions [delete]
+func (app *application) deleteOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getAuthUserFromContext(r)
+	currentSessionID := getSessionIDFromContext(r)
+
+	if err := app.models.Sessions.RevokeOthers(r.Context(), user.ID, currentSessionID); err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"social/internal/models"
+	"strings"
+	"time"
+)
+
+func loginAccountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginRetryAfter reports how long the caller has to wait before another login
+// attempt for the account and IP is accepted. Zero means go ahead.
+func (app *application) loginRetryAfter(ctx context.Context, email, ip string) (time.Duration, error) {
+	keys := []string{loginAccountKey(email)}
+	if ip != "" {
+		keys = append(keys, loginIPKey(ip))
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		attempt, err := app.models.LoginAttempts.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			wait = max(wait, attempt.LockedUntil.Sub(now))
+		}
+		if attempt.Failures > 0 {
+			next := attempt.LastFailedAt.Add(loginDelay(app.config.auth.lockout, attempt.Failures))
+			wait = max(wait, next.Sub(now))
+		}
+	}
+	return wait, nil
+}
+
+// loginDelay is the pause enforced after the given number of consecutive
+// failures: nothing for the first couple of typos, then doubling up to maxDelay.
+func loginDelay(cfg LockoutConfig, failures int) time.Duration {
+	if failures < cfg.freeFailures {
+		return 0
+	}
+	delay := cfg.baseDelay
+	for i := cfg.freeFailures; i < failures && delay < cfg.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, cfg.maxDelay)
+}
+
+// recordLoginFailure counts a failed attempt against the account and the IP,
+// locking either one once it crosses its threshold. user is nil when the email
+// does not belong to anyone; otherwise its owner is told about the lock.
+func (app *application) recordLoginFailure(ctx context.Context, email, ip string, user *models.User) error {
+	cfg := app.config.auth.lockout
+	lockedUntil := time.Now().Add(cfg.duration)
+
+	attempt, err := app.models.LoginAttempts.RecordFailure(ctx, loginAccountKey(email), cfg.window)
+	if err != nil {
+		return err
+	}
+	if attempt.Failures >= cfg.maxAccountFailures {
+		var notice *models.AccountLockedEvent
+		if user != nil {
+			notice = &models.AccountLockedEvent{
+				UserID:      user.ID,
+				Username:    user.Username,
+				Email:       user.Email,
+				IP:          ip,
+				LockedUntil: lockedUntil,
+			}
+		}
+		if err := app.models.LoginAttempts.Lock(ctx, attempt.Key, lockedUntil, notice); err != nil {
+			return err
+		}
+		app.logger.Warnw("account locked after repeated login failures", "email", email, "ip", ip)
+	}
+
+	if ip == "" {
+		return nil
+	}
+	attempt, err = app.models.LoginAttempts.RecordFailure(ctx, loginIPKey(ip), cfg.window)
+	if err != nil {
+		return err
+	}
+	if attempt.Failures >= cfg.maxIPFailures {
+		if err := app.models.LoginAttempts.Lock(ctx, attempt.Key, lockedUntil, nil); err != nil {
+			return err
+		}
+		app.logger.Warnw("ip locked after repeated login failures", "ip", ip)
+	}
+	return nil
+}
+
+// throttleEmailRequest counts a request that mails a token for action
+// ("password_reset", "magic_link", ...) against the address and the IP, in the
+// login attempts store under keys of their own. Past either limit the key is
+// locked for the rest of the window. It returns how long the caller has to
+// wait; zero means go ahead. Requests for unknown addresses count the same so
+// the answer reveals nothing about which accounts exist.
+func (app *application) throttleEmailRequest(ctx context.Context, action, email, ip string) (time.Duration, error) {
+	cfg := app.config.auth.emailRequests
+	limits := map[string]int{action + ":" + loginAccountKey(email): cfg.maxPerEmail}
+	if ip != "" {
+		limits[action+":"+loginIPKey(ip)] = cfg.maxPerIP
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for key := range limits {
+		attempt, err := app.models.LoginAttempts.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			wait = max(wait, attempt.LockedUntil.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return wait, nil
+	}
+
+	for key, limit := range limits {
+		attempt, err := app.models.LoginAttempts.RecordFailure(ctx, key, cfg.window)
+		if err != nil {
+			return 0, err
+		}
+		if attempt.Failures > limit {
+			if err := app.models.LoginAttempts.Lock(ctx, key, now.Add(cfg.window), nil); err != nil {
+				return 0, err
+			}
+			wait = max(wait, cfg.window)
+		}
+	}
+	return wait, nil
+}
+
+// clearLoginFailures forgets the failures of an account after it logged in.
+// The IP counter is left alone so one valid account cannot be used to keep
+// guessing others from the same address.
+func (app *application) clearLoginFailures(ctx context.Context, email string) error {
+	return app.models.LoginAttempts.Clear(ctx, loginAccountKey(email))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"social/internal/models"
+	"testing"
+	"time"
+)
+
+const testPassword = "correct horse battery staple"
+
+// lockoutTest runs the API with one activated user whose password is
+// testPassword.
+type lockoutTest struct {
+	app      *application
+	attempts *models.InMemoryLoginAttempts
+	api      *httptest.Server
+	user     *models.User
+}
+
+func newLockoutTest(t *testing.T) *lockoutTest {
+	t.Helper()
+
+	app := newTestApplication(t)
+	hash, err := app.hashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := app.models.Users.(*fakeUsers).add(models.User{
+		Username:    "alice",
+		Email:       "alice@example.com",
+		Password:    string(hash),
+		IsActivated: true,
+	})
+	api := httptest.NewServer(app.router())
+	t.Cleanup(api.Close)
+
+	return &lockoutTest{
+		app:      app,
+		attempts: app.models.LoginAttempts.(*models.InMemoryLoginAttempts),
+		api:      api,
+		user:     user,
+	}
+}
+
+func (l *lockoutTest) login(t *testing.T, pass string, wantStatus int) {
+	t.Helper()
+
+	body, err := json.Marshal(loginPayload{Email: l.user.Email, Password: pass})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(l.api.URL+"/v1/auth/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		var problem ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&problem)
+		t.Fatalf("login: got status %d (%s), want %d", resp.StatusCode, problem.Detail, wantStatus)
+	}
+}
+
+func (l *lockoutTest) failures(t *testing.T) int {
+	t.Helper()
+
+	attempt, err := l.attempts.Get(context.Background(), loginAccountKey(l.user.Email))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attempt.Failures
+}
+
+func TestLoginLocksAccountAfterRepeatedFailures(t *testing.T) {
+	l := newLockoutTest(t)
+	maxFailures := l.app.config.auth.lockout.maxAccountFailures
+
+	for range maxFailures - 1 {
+		l.login(t, "wrong password", http.StatusUnauthorized)
+	}
+	if notices := l.attempts.Notices(); len(notices) != 0 {
+		t.Fatalf("account locked before %d failures: %+v", maxFailures, notices)
+	}
+
+	l.login(t, "wrong password", http.StatusUnauthorized)
+	// the right password is refused while the lock holds
+	l.login(t, testPassword, http.StatusTooManyRequests)
+
+	notices := l.attempts.Notices()
+	if len(notices) != 1 || notices[0].UserID != l.user.ID || notices[0].Email != l.user.Email {
+		t.Fatalf("expected one lock notice for %s, got %+v", l.user.Email, notices)
+	}
+}
+
+func TestLoginLockExpires(t *testing.T) {
+	l := newLockoutTest(t)
+	l.app.config.auth.lockout.duration = 50 * time.Millisecond
+
+	for range l.app.config.auth.lockout.maxAccountFailures {
+		l.login(t, "wrong password", http.StatusUnauthorized)
+	}
+	l.login(t, testPassword, http.StatusTooManyRequests)
+
+	time.Sleep(l.app.config.auth.lockout.duration)
+	l.login(t, testPassword, http.StatusOK)
+}
+
+func TestSuccessfulLoginResetsFailures(t *testing.T) {
+	l := newLockoutTest(t)
+	maxFailures := l.app.config.auth.lockout.maxAccountFailures
+
+	for range maxFailures - 1 {
+		l.login(t, "wrong password", http.StatusUnauthorized)
+	}
+	l.login(t, testPassword, http.StatusOK)
+	if got := l.failures(t); got != 0 {
+		t.Fatalf("got %d failures after a successful login, want 0", got)
+	}
+
+	// without the reset this would cross the threshold
+	for range maxFailures - 1 {
+		l.login(t, "wrong password", http.StatusUnauthorized)
+	}
+	l.login(t, testPassword, http.StatusOK)
+	if notices := l.attempts.Notices(); len(notices) != 0 {
+		t.Fatalf("account locked although every run of failures ended in a login: %+v", notices)
+	}
+}
+
+func TestForgotPasswordIsThrottledPerEmail(t *testing.T) {
+	l := newLockoutTest(t)
+	forgot := func(email string, wantStatus int) {
+		t.Helper()
+
+		body, err := json.Marshal(forgotPasswordPayload{Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Post(l.api.URL+"/v1/auth/password/forgot", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("forgot password for %s: got status %d, want %d", email, resp.StatusCode, wantStatus)
+		}
+	}
+
+	// an unknown address is counted like a real one
+	for range l.app.config.auth.emailRequests.maxPerEmail {
+		forgot("nobody@example.com", http.StatusAccepted)
+	}
+	forgot("nobody@example.com", http.StatusTooManyRequests)
+	forgot("someone-else@example.com", http.StatusAccepted)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"social/internal/models"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// personalAccessTokenPayload represents the payload to mint a personal access token
+// swagger:model personalAccessTokenPayload
+type personalAccessTokenPayload struct {
+	// Name to recognise the token by
+	// example: release bot
+	Name string `json:"name" validate:"required,max=100" example:"release bot"`
+	// Scopes granted to the token
+	// example: ["posts:write"]
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=posts:read posts:write comments:write reactions:write feed:read users:read users:write admin" example:"posts:write"`
+	// Optional expiry, RFC3339
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty"`
+}
+
+// createPersonalAccessTokenHandler godoc
+//
+//	@Summary		Create a personal access token
+//	@Description	Mints a named, scoped token for scripts and bots. The token value is only returned once.
+//	@Tags			Tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		personalAccessTokenPayload	true	"Token payload"
+//	@Success		201		{object}	DataResponsePersonalAccessToken
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/tokens [post]
+func (app *application) createPersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload personalAccessTokenPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+	if payload.ExpiresAt != nil && payload.ExpiresAt.Before(time.Now()) {
+		app.errorBadRequest(w, r, errors.New("expires_at must be in the future"))
+		return
+	}
+
+	user := getAuthUserFromContext(r)
+
+	if slices.Contains(payload.Scopes, models.ScopeAdmin) {
+		allowed, err := app.checkRolePrecedence(r.Context(), user, models.RoleAdmin)
+		if err != nil {
+			app.errorServerError(w, r, err)
+			return
+		}
+		if !allowed {
+			app.errorForbidden(w, r, errors.New("only admins can mint tokens with the admin scope"))
+			return
+		}
+	}
+
+	token, err := models.NewPersonalAccessToken(user.ID, payload.Name, payload.Scopes, payload.ExpiresAt)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+	if err := app.models.PersonalAccessTokens.Create(r.Context(), token); err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// getPersonalAccessTokensHandler godoc
+//
+//	@Summary		List personal access tokens
+//	@Description	Lists the authenticated user's personal access tokens, without their values
+//	@Tags			Tokens
+//	@Produce		json
+//	@Success		200	{object}	DataResponsePersonalAccessTokens
+//	@Failure		401	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/tokens [get]
+func (app *application) getPersonalAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := getAuthUserFromContext(r)
+
+	tokens, err := app.models.PersonalAccessTokens.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.errorServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
+
+// deletePersonalAccessTokenHandler godoc
+//
+//	@Summary		Delete a personal access token
+//	@Description	Revokes one of the authenticated user's personal access tokens
+//	@Tags			Tokens
+//	@Param			tokenID	path	string	true	"Token ID (UUID)"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/tokens/{tokenID} [delete]
+func (app *application) deletePersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := uuid.Parse(chi.URLParam(r, "tokenID"))
+	if err != nil {
+		app.errorBadRequest(w, r, err)
+		return
+	}
+	user := getAuthUserFromContext(r)
+
+	err = app.models.PersonalAccessTokens.Delete(r.Context(), user.ID, tokenID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrPersonalAccessTokenNotFound):
+			app.errorNotFound(w, r, err)
+			return
+		default:
+			app.errorServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.errorServerError(w, r, err)
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"errors"
 	"net/http"
 	"social/internal/models"
-
-	"github.com/google/uuid"
 )
 
 // getUserHandler godoc
@@ -16,8 +14,11 @@ import (
 //	@Produce		json
 //	@Param			userID	path		string	true	"User ID (UUID)"
 //	@Success		200		{object}	DataResponseUser
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
 //	@Failure		404		{object}	ErrorResponse
 //	@Failure		500		{object}	ErrorResponse
+//	@Security		ApiKeyAuth
 //	@Router			/users/{userID} [get]
 func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
@@ -32,19 +33,20 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 //	@Summary		Follow a user
 //	@Description	Follow the specified user
 //	@Tags			Users
-//	@Param			userID	path	string	true	"User ID (UUID)"
-//	@Success		204		"No Content"
-//	@Failure		400		{object}	ErrorResponse
-//	@Failure		500		{object}	ErrorResponse
+//	@Param			userID			path	string	true	"User ID (UUID)"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of this request return the first response"
+//	@Success		204				"No Content"
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		401				{object}	ErrorResponse
+//	@Failure		403				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Security		ApiKeyAuth
 //	@Router			/users/{userID}/follow [put]
 func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request) {
-	// follow request -> user_id in request + target_id -> simply insert into db
-	// todo: fetch userID from context when auth is implemented
-	userID := "b58e1f73-028f-4c17-b8ac-8a3b416c69fd"
-	userUUID := uuid.MustParse(userID)
+	follower := getAuthUserFromContext(r)
 	user := getUserFromContext(r)
 
-	err := app.models.Users.Follow(r.Context(), user.ID, userUUID)
+	err := app.models.Users.Follow(r.Context(), user.ID, follower.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrForeignKeyViolation):
@@ -66,16 +68,19 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 //	@Summary		Unfollow a user
 //	@Description	Unfollow the specified user
 //	@Tags			Users
-//	@Param			userID	path	string	true	"User ID (UUID)"
-//	@Success		204		"No Content"
-//	@Failure		500		{object}	ErrorResponse
+//	@Param			userID			path	string	true	"User ID (UUID)"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of this request return the first response"
+//	@Success		204				"No Content"
+//	@Failure		401				{object}	ErrorResponse
+//	@Failure		403				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Security		ApiKeyAuth
 //	@Router			/users/{userID}/unfollow [put]
 func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
-	userID := "b58e1f73-028f-4c17-b8ac-8a3b416c69fd"
-	userUUID := uuid.MustParse(userID)
+	follower := getAuthUserFromContext(r)
 	user := getUserFromContext(r)
 
-	err := app.models.Users.Unfollow(r.Context(), user.ID, userUUID)
+	err := app.models.Users.Unfollow(r.Context(), user.ID, follower.ID)
 	if err != nil {
 		app.errorServerError(w, r, err)
 		return
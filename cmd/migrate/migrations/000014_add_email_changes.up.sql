@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS email_changes (
+    token_hash BYTEA PRIMARY KEY,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    new_email citext NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
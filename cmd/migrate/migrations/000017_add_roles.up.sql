@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    level INT NOT NULL DEFAULT 0,
+    description TEXT
+);
+
+INSERT INTO roles (id, name, level, description)
+VALUES (1, 'user', 1, 'A user can create posts and comments and edit or delete their own posts'),
+       (2, 'moderator', 2, 'A moderator can update other users posts'),
+       (3, 'admin', 3, 'An admin can update and delete other users posts')
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role_id BIGINT NOT NULL DEFAULT 1 REFERENCES roles(id);
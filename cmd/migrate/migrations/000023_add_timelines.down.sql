@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_followers_follower_id;
+DROP TABLE IF EXISTS timelines;
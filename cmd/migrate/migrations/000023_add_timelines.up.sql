@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS timelines (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_timelines_user_id_created_at ON timelines (user_id, created_at, post_id);
+CREATE INDEX IF NOT EXISTS idx_timelines_user_id_author_id ON timelines (user_id, author_id);
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers (follower_id);
+
+-- materialize the timelines of existing follows. Authors with at least the
+-- default TIMELINE_CELEBRITY_THRESHOLD of 10000 followers are left out like
+-- on fan-out: their posts are merged in when the feed is read.
+WITH fanned_out AS (
+    SELECT user_id FROM followers GROUP BY user_id HAVING COUNT(*) < 10000
+)
+INSERT INTO timelines (user_id, post_id, author_id, created_at)
+SELECT f.follower_id, p.id, p.user_id, p.created_at
+FROM followers f
+JOIN fanned_out a ON a.user_id = f.user_id
+JOIN posts p ON p.user_id = f.user_id
+ON CONFLICT DO NOTHING;
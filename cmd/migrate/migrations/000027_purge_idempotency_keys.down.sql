@@ -0,0 +1 @@
+-- the purged responses cannot be restored
@@ -0,0 +1,3 @@
+-- responses stored before the middleware was limited to content routes may
+-- hold personal access tokens and TOTP secrets
+DELETE FROM idempotency_keys;
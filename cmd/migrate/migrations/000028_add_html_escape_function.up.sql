@@ -0,0 +1,8 @@
+-- escapes text for HTML, so search snippets can be highlighted with markup
+-- without letting the markup of user content through
+CREATE OR REPLACE FUNCTION html_escape(t text) RETURNS text
+    LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE
+    AS $$
+        SELECT replace(replace(replace(replace(replace(t,
+            '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')
+    $$;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS count_followers ON followers;
+DROP FUNCTION IF EXISTS count_followers();
+ALTER TABLE users DROP COLUMN IF EXISTS followers_count;
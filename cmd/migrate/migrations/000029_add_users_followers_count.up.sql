@@ -0,0 +1,27 @@
+-- the follower count decides whether an author's posts are fanned out, so it
+-- is kept on the user instead of being counted on every post and feed read.
+-- A trigger keeps it in step with every write to followers, including those
+-- made outside the API and by cascading deletes.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS followers_count INTEGER NOT NULL DEFAULT 0;
+
+LOCK TABLE followers IN SHARE MODE;
+
+CREATE OR REPLACE FUNCTION count_followers() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+        BEGIN
+            IF TG_OP = 'INSERT' THEN
+                UPDATE users SET followers_count = followers_count + 1 WHERE id = NEW.user_id;
+            ELSE
+                UPDATE users SET followers_count = followers_count - 1 WHERE id = OLD.user_id;
+            END IF;
+            RETURN NULL;
+        END
+    $$;
+
+CREATE OR REPLACE TRIGGER count_followers AFTER INSERT OR DELETE ON followers
+    FOR EACH ROW EXECUTE FUNCTION count_followers();
+
+UPDATE users u SET followers_count = f.count
+FROM (SELECT user_id, COUNT(*) AS count FROM followers GROUP BY user_id) f
+WHERE f.user_id = u.id;
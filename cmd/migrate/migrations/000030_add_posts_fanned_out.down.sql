@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_posts_unfanned_user_id_created_at_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS fanned_out;
@@ -0,0 +1,14 @@
+-- whether a post was copied into its author's followers' timelines. Posts
+-- written while the author had too many followers to fan out stay unfanned
+-- even after the author loses followers, so the feed reads them from posts
+-- by this flag rather than by the author's current follower count.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS fanned_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- existing posts were fanned out by the timelines migration unless their
+-- author had at least the default TIMELINE_CELEBRITY_THRESHOLD of 10000
+-- followers.
+UPDATE posts p SET fanned_out = TRUE
+FROM users u
+WHERE u.id = p.user_id AND u.followers_count < 10000;
+
+CREATE INDEX IF NOT EXISTS idx_posts_unfanned_user_id_created_at_id ON posts (user_id, created_at, id) WHERE NOT fanned_out;
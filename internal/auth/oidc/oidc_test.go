@@ -0,0 +1,109 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"social/internal/auth/oidc"
+	"social/internal/auth/oidc/oidctest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "http://api.test/v1/auth/oidc/stub/callback"
+
+// authorize walks the start of the flow: it builds the authorization URL and
+// has the stub issue a code for it, as the browser redirect would.
+func authorize(t *testing.T, stub *oidctest.Server, provider *oidc.Provider, nonce, verifier string) string {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge") != oidc.CodeChallenge(verifier) || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization URL lacks the PKCE challenge: %s", authURL)
+	}
+	if query.Get("nonce") != nonce || query.Get("state") != "state" {
+		t.Fatalf("authorization URL lacks state or nonce: %s", authURL)
+	}
+	return stub.Authorize(query.Get("nonce"), query.Get("code_challenge"), query.Get("redirect_uri"))
+}
+
+func TestExchange(t *testing.T) {
+	stub := oidctest.NewServer(t)
+	stub.Identity = oidctest.Identity{Subject: "alice", Email: "alice@example.com", EmailVerified: true}
+	provider := oidc.NewProvider(stub.Config(redirectURL), nil)
+
+	code := authorize(t, stub, provider, "nonce", "verifier")
+	claims, err := provider.Exchange(context.Background(), code, "verifier", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "alice" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(jwt.MapClaims)
+		verifier string
+		nonce    string
+		want     error
+	}{
+		{name: "nonce mismatch", verifier: "verifier", nonce: "other nonce", want: oidc.ErrInvalidIDToken},
+		{name: "missing nonce", verifier: "verifier", nonce: "nonce", tamper: func(c jwt.MapClaims) { delete(c, "nonce") }, want: oidc.ErrInvalidIDToken},
+		{name: "wrong audience", verifier: "verifier", nonce: "nonce", tamper: func(c jwt.MapClaims) { c["aud"] = "someone-else" }, want: oidc.ErrInvalidIDToken},
+		{name: "wrong issuer", verifier: "verifier", nonce: "nonce", tamper: func(c jwt.MapClaims) { c["iss"] = "https://evil.test" }, want: oidc.ErrInvalidIDToken},
+		{name: "PKCE verifier mismatch", verifier: "other verifier", nonce: "nonce", want: oidc.ErrExchange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := oidctest.NewServer(t)
+			stub.Identity = oidctest.Identity{Subject: "alice"}
+			stub.TamperClaims = tt.tamper
+			provider := oidc.NewProvider(stub.Config(redirectURL), nil)
+
+			code := authorize(t, stub, provider, "nonce", "verifier")
+			_, err := provider.Exchange(context.Background(), code, tt.verifier, tt.nonce)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownKeyIDRefetchesKeysAtMostOncePerInterval(t *testing.T) {
+	stub := oidctest.NewServer(t)
+	stub.Identity = oidctest.Identity{Subject: "alice"}
+	provider := oidc.NewProvider(stub.Config(redirectURL), &http.Client{})
+
+	code := authorize(t, stub, provider, "nonce", "verifier")
+	if _, err := provider.Exchange(context.Background(), code, "verifier", "nonce"); err != nil {
+		t.Fatal(err)
+	}
+	if got := stub.JWKSRequests(); got != 1 {
+		t.Fatalf("fetched keys %d times for the first token, want 1", got)
+	}
+
+	stub.KeyID = "forged"
+	for range 5 {
+		code := authorize(t, stub, provider, "nonce", "verifier")
+		if _, err := provider.Exchange(context.Background(), code, "verifier", "nonce"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+			t.Fatalf("got error %v for an unknown key id, want %v", err, oidc.ErrInvalidIDToken)
+		}
+	}
+	if got := stub.JWKSRequests(); got != 1 {
+		t.Fatalf("fetched keys %d times, want no refetch within the refresh interval", got)
+	}
+}
@@ -0,0 +1,93 @@
+// Package bloom is a Bloom filter for membership checks against large, fixed
+// sets such as lists of breached passwords. A filter never misses a member it
+// was built with but may report a few non-members as present, at a rate chosen
+// when it is created.
+package bloom
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var ErrInvalidFilter = errors.New("bloom: invalid filter encoding")
+
+// headerLength is the encoded size of the hash count and bit count that
+// precede the bit set.
+const headerLength = 12
+
+type Filter struct {
+	bits   []byte
+	m      uint64
+	hashes uint32
+}
+
+// New returns an empty filter sized for n members with the given false
+// positive rate.
+func New(n int, falsePositiveRate float64) *Filter {
+	n = max(n, 1)
+	m := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+
+	bits := uint64(m)
+	return &Filter{
+		bits:   make([]byte, (bits+7)/8),
+		m:      bits,
+		hashes: uint32(max(k, 1)),
+	}
+}
+
+func (f *Filter) Add(member string) {
+	h1, h2 := hash(member)
+	for i := range uint64(f.hashes) {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Contains reports whether member may be in the set. false is definite.
+func (f *Filter) Contains(member string) bool {
+	h1, h2 := hash(member)
+	for i := range uint64(f.hashes) {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalBinary encodes the filter as its hash count and bit count, both
+// big-endian, followed by the bit set.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	data := make([]byte, headerLength, headerLength+len(f.bits))
+	binary.BigEndian.PutUint32(data, f.hashes)
+	binary.BigEndian.PutUint64(data[4:], f.m)
+	return append(data, f.bits...), nil
+}
+
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	if len(data) < headerLength {
+		return ErrInvalidFilter
+	}
+	hashes := binary.BigEndian.Uint32(data)
+	m := binary.BigEndian.Uint64(data[4:])
+	bits := data[headerLength:]
+	if hashes == 0 || m == 0 || uint64(len(bits)) != (m+7)/8 {
+		return ErrInvalidFilter
+	}
+
+	f.bits = append([]byte(nil), bits...)
+	f.m = m
+	f.hashes = hashes
+	return nil
+}
+
+// hash derives the two base hashes combined into the filter's k positions
+// (Kirsch and Mitzenmacher). h2 is odd so the positions never collapse into
+// one.
+func hash(member string) (uint64, uint64) {
+	sum := sha256.Sum256([]byte(member))
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16]) | 1
+}
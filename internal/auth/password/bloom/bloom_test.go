@@ -0,0 +1,50 @@
+package bloom
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	const members = 10000
+	const falsePositiveRate = 0.001
+
+	filter := New(members, falsePositiveRate)
+	for i := range members {
+		filter.Add("member-" + strconv.Itoa(i))
+	}
+
+	data, err := filter.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Filter
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range members {
+		if !decoded.Contains("member-" + strconv.Itoa(i)) {
+			t.Fatalf("member-%d is missing", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := range members {
+		if decoded.Contains("stranger-" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / members; rate > falsePositiveRate*3 {
+		t.Fatalf("false positive rate %.4f, want about %.4f", rate, falsePositiveRate)
+	}
+}
+
+func TestUnmarshalRejectsTruncatedFilter(t *testing.T) {
+	data, _ := New(100, 0.01).MarshalBinary()
+
+	var f Filter
+	if err := f.UnmarshalBinary(data[:len(data)-1]); err != ErrInvalidFilter {
+		t.Fatalf("got %v, want %v", err, ErrInvalidFilter)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build ignore
+
+// gen_common_passwords builds the Bloom filter embedded by the policy from a
+// gzip compressed, newline separated password list, most common first:
+//
+//	go run gen_common_passwords.go -in common_passwords.txt.gz -out common_passwords.bloom
+//
+// The bundled list merges the original top 400 with the frequency list
+// shipped with zxcvbn (MIT). Any larger list, such as the top 100,000 of a
+// public breach compilation, can be dropped in instead; the filter is sized
+// from the number of entries.
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"log"
+	"os"
+	"social/internal/auth/password/bloom"
+	"strings"
+)
+
+// falsePositiveRate is the share of passwords missing from the list that are
+// still refused as common.
+const falsePositiveRate = 0.001
+
+func main() {
+	in := flag.String("in", "common_passwords.txt.gz", "gzip compressed password list")
+	out := flag.String("out", "common_passwords.bloom", "filter to write")
+	flag.Parse()
+
+	passwords, err := readList(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	filter := bloom.New(len(passwords), falsePositiveRate)
+	for _, p := range passwords {
+		filter.Add(p)
+	}
+	data, err := filter.MarshalBinary()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d passwords to %s (%d bytes)", len(passwords), *out, len(data))
+}
+
+// readList returns the distinct, lower-cased entries of the list at path.
+func readList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	seen := make(map[string]struct{})
+	var passwords []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if _, ok := seen[line]; ok || line == "" {
+			continue
+		}
+		seen[line] = struct{}{}
+		passwords = append(passwords, line)
+	}
+	return passwords, scanner.Err()
+}
@@ -0,0 +1,174 @@
+// Package password hashes and verifies user passwords. Hashes are stored in a
+// self-describing format so the algorithm and its parameters can change
+// without invalidating what is already in the database:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>   (current)
+//	$2a$10$...                                     (legacy bcrypt)
+//
+// Salt and key are unpadded standard base64, as in the PHC string format.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrMismatch      = errors.New("password does not match")
+	ErrUnknownFormat = errors.New("unknown password hash format")
+	ErrInvalidHash   = errors.New("malformed password hash")
+	ErrInvalidParams = errors.New("invalid argon2id parameters")
+)
+
+// Params are the argon2id cost parameters. Memory is in KiB.
+type Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams follow the OWASP recommendation for argon2id at the time of
+// writing, with a bit more memory than the minimum.
+var DefaultParams = Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Bounds accepted by NewParams. The memory floor is the weakest argon2id
+// setting OWASP recommends; the ceilings keep a typo from making every login
+// allocate a gigabyte or run for seconds.
+const (
+	minMemory      = 7 * 1024
+	maxMemory      = 1024 * 1024
+	maxIterations  = 32
+	maxParallelism = 255
+)
+
+// NewParams returns DefaultParams with the given cost. Values outside the
+// bounds above are refused rather than truncated to the field sizes.
+func NewParams(memory, iterations, parallelism int) (Params, error) {
+	switch {
+	case memory < minMemory || memory > maxMemory:
+		return Params{}, fmt.Errorf("%w: memory must be between %d and %d KiB, got %d", ErrInvalidParams, minMemory, maxMemory, memory)
+	case iterations < 1 || iterations > maxIterations:
+		return Params{}, fmt.Errorf("%w: iterations must be between 1 and %d, got %d", ErrInvalidParams, maxIterations, iterations)
+	case parallelism < 1 || parallelism > maxParallelism:
+		return Params{}, fmt.Errorf("%w: parallelism must be between 1 and %d, got %d", ErrInvalidParams, maxParallelism, parallelism)
+	}
+
+	params := DefaultParams
+	params.Memory = uint32(memory)
+	params.Iterations = uint32(iterations)
+	params.Parallelism = uint8(parallelism)
+	return params, nil
+}
+
+var b64 = base64.RawStdEncoding
+
+// Hash derives an argon2id hash of password with params.
+func Hash(password string, params Params) ([]byte, error) {
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism, b64.EncodeToString(salt), b64.EncodeToString(key))
+	return []byte(encoded), nil
+}
+
+// Verify checks password against hash, whichever supported format it is in.
+// It returns ErrMismatch when the password is wrong.
+func Verify(hash []byte, password string) error {
+	switch {
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatch
+		}
+		return err
+	case strings.HasPrefix(string(hash), "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return err
+		}
+		other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return ErrMismatch
+		}
+		return nil
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// NeedsRehash reports whether hash should be replaced by a fresh Hash with
+// params: it uses another algorithm or weaker/different argon2id parameters.
+func NeedsRehash(hash []byte, params Params) bool {
+	if !strings.HasPrefix(string(hash), "$argon2id$") {
+		return true
+	}
+	current, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	current.SaltLength = uint32(len(salt))
+	current.KeyLength = uint32(len(key))
+	return current != params
+}
+
+func isBcrypt(hash []byte) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(string(hash), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeArgon2id(hash []byte) (Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return Params{}, nil, nil, fmt.Errorf("%w: unsupported argon2 version %d", ErrInvalidHash, version)
+	}
+
+	var params Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,40 @@
+package password
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewParams(t *testing.T) {
+	tests := []struct {
+		name                            string
+		memory, iterations, parallelism int
+		wantErr                         bool
+	}{
+		{name: "defaults", memory: 64 * 1024, iterations: 3, parallelism: 2},
+		{name: "zero memory", memory: 0, iterations: 3, parallelism: 2, wantErr: true},
+		{name: "memory below the floor", memory: minMemory - 1, iterations: 3, parallelism: 2, wantErr: true},
+		{name: "memory that would wrap", memory: 1 << 32, iterations: 3, parallelism: 2, wantErr: true},
+		{name: "zero iterations", memory: 64 * 1024, iterations: 0, parallelism: 2, wantErr: true},
+		{name: "zero parallelism", memory: 64 * 1024, iterations: 3, parallelism: 0, wantErr: true},
+		{name: "parallelism that would wrap to zero", memory: 64 * 1024, iterations: 3, parallelism: 256, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := NewParams(tt.memory, tt.iterations, tt.parallelism)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidParams) {
+					t.Fatalf("got %+v, %v; want %v", params, err, ErrInvalidParams)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if params != DefaultParams {
+				t.Fatalf("got %+v, want %+v", params, DefaultParams)
+			}
+		})
+	}
+}
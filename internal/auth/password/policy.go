@@ -0,0 +1,67 @@
+package password
+
+import (
+	_ "embed"
+	"social/internal/auth/password/bloom"
+	"strings"
+	"sync"
+)
+
+// commonPasswords is a Bloom filter of the lower-cased passwords seen most
+// often in public breach corpora, built from common_passwords.txt.gz. About
+// one in a thousand other passwords is refused as well.
+//
+//go:generate go run gen_common_passwords.go -in common_passwords.txt.gz -out common_passwords.bloom
+//go:embed common_passwords.bloom
+var commonPasswords []byte
+
+var (
+	commonOnce   sync.Once
+	commonFilter bloom.Filter
+)
+
+// minIdentifierLength keeps very short usernames or email local parts from
+// rejecting half of all passwords.
+const minIdentifierLength = 3
+
+// PolicyError explains why a password was rejected, tied to the request field
+// the caller should point at.
+type PolicyError struct {
+	Field   string
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Check rejects passwords that are well known or built from the account's own
+// username or email. field is the name the error is reported under.
+func Check(field, plaintext, username, email string) error {
+	lowered := strings.ToLower(plaintext)
+
+	if isCommon(lowered) {
+		return &PolicyError{Field: field, Message: "is too common, choose a less predictable password"}
+	}
+	if username = strings.ToLower(username); len(username) >= minIdentifierLength && strings.Contains(lowered, username) {
+		return &PolicyError{Field: field, Message: "must not contain your username"}
+	}
+	email = strings.ToLower(email)
+	local, _, _ := strings.Cut(email, "@")
+	if len(local) >= minIdentifierLength && strings.Contains(lowered, local) {
+		return &PolicyError{Field: field, Message: "must not contain your email address"}
+	}
+	return nil
+}
+
+func isCommon(lowered string) bool {
+	commonOnce.Do(loadCommonPasswords)
+	return commonFilter.Contains(lowered)
+}
+
+func loadCommonPasswords() {
+	if err := commonFilter.UnmarshalBinary(commonPasswords); err != nil {
+		// the filter is embedded at build time, so this is a broken build
+		panic("password: reading common password filter: " + err.Error())
+	}
+}
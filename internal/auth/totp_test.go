@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 Appendix B test vectors,
+// "12345678901234567890", base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPMatchesRFC6238Vectors(t *testing.T) {
+	// Appendix B lists 8-digit codes; a 6-digit code is their last six digits.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			at := time.Unix(tt.unix, 0)
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, at)
+			if !ok {
+				t.Fatalf("code %s was rejected at %d", tt.code, tt.unix)
+			}
+			if want := tt.unix / totpPeriod; step != want {
+				t.Fatalf("got step %d, want %d", step, want)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPEnforcesSkewWindow(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Unix(1234567890, 0)
+	current := at.Unix() / totpPeriod
+
+	tests := []struct {
+		name   string
+		offset int64
+		want   bool
+	}{
+		{name: "two steps behind", offset: -totpSkew - 1, want: false},
+		{name: "one step behind", offset: -totpSkew, want: true},
+		{name: "current step", offset: 0, want: true},
+		{name: "one step ahead", offset: totpSkew, want: true},
+		{name: "two steps ahead", offset: totpSkew + 1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, totpCode(key, current+tt.offset), at)
+			if ok != tt.want {
+				t.Fatalf("got %v, want %v", ok, tt.want)
+			}
+			if ok && step != current+tt.offset {
+				t.Fatalf("got step %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsMalformedInput(t *testing.T) {
+	at := time.Unix(59, 0)
+	tests := []struct {
+		name, secret, code string
+	}{
+		{name: "8-digit code", secret: rfc6238Secret, code: "94287082"},
+		{name: "short code", secret: rfc6238Secret, code: "28708"},
+		{name: "secret that is not base32", secret: "not base32!", code: "287082"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, at); ok {
+				t.Fatal("malformed input was accepted")
+			}
+		})
+	}
+}
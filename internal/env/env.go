@@ -1,6 +1,7 @@
 package env
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 )
@@ -24,3 +25,17 @@ func GetInt(key string, fallback int) int {
 	}
 	return iVal
 }
+
+// LookupInt is GetInt for settings where a typo must not silently fall back
+// to the default: a value that is set but not an integer is an error.
+func LookupInt(key string, fallback int) (int, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback, nil
+	}
+	iVal, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not an integer", key, val)
+	}
+	return iVal, nil
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"io"
+	"net/mail"
+	"sync"
+)
+
+// LogMailer writes rendered emails to w instead of delivering them. It is
+// meant for development, pointed at stdout or a file.
+type LogMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from *mail.Address
+}
+
+func NewLogMailer(w io.Writer, fromEmail string) *LogMailer {
+	return &LogMailer{
+		w:    w,
+		from: &mail.Address{Name: FromName, Address: fromEmail},
+	}
+}
+
+func (m *LogMailer) Send(templateFile, username, email string, data any) error {
+	msg, err := render(templateFile, m.from.String(), username, email, data)
+	if err != nil {
+		return err
+	}
+	body, err := msg.bytes()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.w.Write(body); err != nil {
+		return err
+	}
+	_, err = io.WriteString(m.w, "\r\n\r\n")
+	return err
+}
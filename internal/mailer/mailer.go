@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	FromName                   = "Social"
+	UserInvitationTemplate     = "user_invitation.tmpl"
+	PasswordResetTemplate      = "password_reset.tmpl"
+	EmailChangeConfirmTemplate = "email_change_confirm.tmpl"
+	EmailChangeNoticeTemplate  = "email_change_notice.tmpl"
+	AccountLockedTemplate      = "account_locked.tmpl"
+	MagicLinkTemplate          = "magic_link.tmpl"
+)
+
+//go:embed "templates"
+var FS embed.FS
+
+type Mailer interface {
+	Send(templateFile, username, email string, data any) error
+}
+
+// message is a rendered email ready to be handed to a transport.
+type message struct {
+	from     string
+	to       string
+	subject  string
+	plain    string
+	html     string
+	sentDate time.Time
+}
+
+// render executes the "subject", "plainBody" and "htmlBody" blocks of the
+// given template with data. The HTML part goes through html/template so that
+// user-controlled values are escaped; the subject and plain text parts must not
+// be, so they are rendered with text/template.
+func render(templateFile, from, username, email string, data any) (*message, error) {
+	textTmpl, err := texttemplate.New("email").ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		return nil, err
+	}
+	htmlTmpl, err := template.New("email").ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := new(bytes.Buffer)
+	if err := textTmpl.ExecuteTemplate(subject, "subject", data); err != nil {
+		return nil, err
+	}
+	plainBody := new(bytes.Buffer)
+	if err := textTmpl.ExecuteTemplate(plainBody, "plainBody", data); err != nil {
+		return nil, err
+	}
+	htmlBody := new(bytes.Buffer)
+	if err := htmlTmpl.ExecuteTemplate(htmlBody, "htmlBody", data); err != nil {
+		return nil, err
+	}
+
+	return &message{
+		from:     from,
+		to:       (&mail.Address{Name: username, Address: email}).String(),
+		subject:  subject.String(),
+		plain:    plainBody.String(),
+		html:     htmlBody.String(),
+		sentDate: time.Now(),
+	}, nil
+}
+
+// bytes encodes the message as a multipart/alternative MIME document.
+func (m *message) bytes() ([]byte, error) {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", m.plain},
+		{"text/html; charset=UTF-8", m.html},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	msg := new(bytes.Buffer)
+	fmt.Fprintf(msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(msg, "To: %s\r\n", m.to)
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", m.subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", m.sentDate.Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", writer.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
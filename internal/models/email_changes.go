@@ -0,0 +1,132 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EmailChangesInterface interface {
+	Create(context.Context, *User, string, time.Duration) (*EmailChange, error)
+	Confirm(context.Context, string) (*EmailChange, error)
+}
+
+// EmailChange is a pending switch of a user's email address, applied once
+// the new address is confirmed with the token sent to it. A user has at most
+// one pending change; requesting another replaces it.
+type EmailChange struct {
+	UserID    uuid.UUID `json:"user_id"`
+	NewEmail  string    `json:"new_email"`
+	Plaintext string    `json:"-"`
+	Hash      []byte    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type EmailChangesModel struct {
+	pool *pgxpool.Pool
+}
+
+// Create stores a pending change for the user and enqueues the confirmation to
+// the new address and the notice to the current one in the same transaction.
+func (e *EmailChangesModel) Create(ctx context.Context, user *User, newEmail string, ttl time.Duration) (*EmailChange, error) {
+	plaintext, hash, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	change := &EmailChange{
+		UserID:    user.ID,
+		NewEmail:  newEmail,
+		Plaintext: plaintext,
+		Hash:      hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	err = executeWithTx(e.pool, ctx, func(tx pgx.Tx) error {
+		statement := `SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND id <> $2)`
+		var taken bool
+		if err := tx.QueryRow(ctx, statement, newEmail, user.ID).Scan(&taken); err != nil {
+			return err
+		}
+		if taken {
+			return ErrDuplicateEmail
+		}
+
+		statement = `
+			INSERT INTO email_changes (token_hash, user_id, new_email, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE
+			SET token_hash = EXCLUDED.token_hash, new_email = EXCLUDED.new_email,
+			    expires_at = EXCLUDED.expires_at, created_at = NOW()
+			RETURNING created_at
+		`
+		if err := tx.QueryRow(ctx, statement, change.Hash, change.UserID, change.NewEmail, change.ExpiresAt).Scan(&change.CreatedAt); err != nil {
+			return err
+		}
+
+		err := enqueueOutboxTx(ctx, tx, OutboxKindEmailChangeRequested, EmailChangeRequestedEvent{
+			UserID:    user.ID,
+			Username:  user.Username,
+			NewEmail:  change.NewEmail,
+			Token:     change.Plaintext,
+			ExpiresAt: change.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+		return enqueueOutboxTx(ctx, tx, OutboxKindEmailChangeNotice, EmailChangeNoticeEvent{
+			UserID:   user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			NewEmail: change.NewEmail,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// Confirm swaps the user's email for the pending one and consumes the token.
+func (e *EmailChangesModel) Confirm(ctx context.Context, plaintext string) (*EmailChange, error) {
+	var change EmailChange
+	err := executeWithTx(e.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			SELECT user_id, new_email, expires_at, created_at
+			FROM email_changes
+			WHERE token_hash = $1
+			FOR UPDATE
+		`
+		err := tx.QueryRow(ctx, statement, hashToken(plaintext)).Scan(&change.UserID, &change.NewEmail, &change.ExpiresAt, &change.CreatedAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrEmailChangeInvalid
+			}
+			return err
+		}
+		if change.ExpiresAt.Before(time.Now()) {
+			return ErrEmailChangeInvalid
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE users SET email = $1 WHERE id = $2`, change.NewEmail, change.UserID)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return ErrDuplicateEmail
+			}
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM email_changes WHERE user_id = $1`, change.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
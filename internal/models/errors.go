@@ -3,5 +3,29 @@ package models
 import "errors"
 
 var (
-	ErrForeignKeyViolation = errors.New("violates foreign key constraint")
+	ErrForeignKeyViolation         = errors.New("violates foreign key constraint")
+	ErrEditConflict                = errors.New("edit conflict")
+	ErrRefreshTokenInvalid         = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused          = errors.New("refresh token has already been used")
+	ErrSessionNotFound             = errors.New("session not found")
+	ErrSessionRevoked              = errors.New("session has been revoked")
+	ErrInviteNotFound              = errors.New("invite not found")
+	ErrInviteExpired               = errors.New("invite has expired")
+	ErrInviteCooldown              = errors.New("invite was sent too recently")
+	ErrInviteLimitReached          = errors.New("invite resend limit reached")
+	ErrPasswordResetInvalid        = errors.New("password reset token is invalid or expired")
+	ErrEmailChangeInvalid          = errors.New("email change token is invalid or expired")
+	ErrDuplicateEmail              = errors.New("email is already in use")
+	ErrTOTPNotEnrolled             = errors.New("two-factor authentication is not enrolled")
+	ErrTOTPAlreadyEnabled          = errors.New("two-factor authentication is already enabled")
+	ErrTOTPCodeReused              = errors.New("two-factor code has already been used")
+	ErrRecoveryCodeInvalid         = errors.New("recovery code is invalid or already used")
+	ErrPersonalAccessTokenInvalid  = errors.New("personal access token is invalid or expired")
+	ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+	ErrDuplicateUsername           = errors.New("username is already in use")
+	ErrIdentityNotFound            = errors.New("identity not found")
+	ErrIdentityAlreadyLinked       = errors.New("identity is already linked to a user")
+	ErrOIDCStateInvalid            = errors.New("sign-in state is invalid or expired")
+	ErrMagicLinkInvalid            = errors.New("magic link is invalid or expired")
+	ErrIdempotencyKeyExists        = errors.New("idempotency key has already been used")
 )
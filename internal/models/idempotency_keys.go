@@ -0,0 +1,110 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdempotencyKeysInterface interface {
+	Reserve(context.Context, *IdempotencyKey) (*IdempotencyKey, error)
+	Complete(context.Context, *IdempotencyKey) error
+	Release(context.Context, uuid.UUID, string) error
+}
+
+// IdempotencyKey records a request made with an Idempotency-Key header so a
+// retry of it is answered with the stored response instead of running again.
+// StatusCode stays nil while the first request is still being handled.
+type IdempotencyKey struct {
+	UserID          uuid.UUID         `json:"user_id"`
+	Key             string            `json:"key"`
+	RequestHash     []byte            `json:"-"`
+	StatusCode      *int              `json:"status_code"`
+	ResponseHeaders map[string]string `json:"-"`
+	ResponseBody    []byte            `json:"-"`
+	ExpiresAt       time.Time         `json:"expires_at"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+type IdempotencyKeysModel struct {
+	pool *pgxpool.Pool
+}
+
+// Reserve claims the key for a new request. When the user already used the
+// key, and it has not expired, nothing is claimed and the earlier record is
+// returned with ErrIdempotencyKeyExists.
+func (i *IdempotencyKeysModel) Reserve(ctx context.Context, key *IdempotencyKey) (*IdempotencyKey, error) {
+	var existing *IdempotencyKey
+	err := executeWithTx(i.pool, ctx, func(tx pgx.Tx) error {
+		// the user's expired keys are swept here so the table stays bounded
+		statement := `DELETE FROM idempotency_keys WHERE user_id = $1 AND expires_at < NOW()`
+		if _, err := tx.Exec(ctx, statement, key.UserID); err != nil {
+			return err
+		}
+
+		statement = `
+			INSERT INTO idempotency_keys (user_id, key, request_hash, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, key) DO NOTHING
+			RETURNING created_at
+		`
+		err := tx.QueryRow(ctx, statement, key.UserID, key.Key, key.RequestHash, key.ExpiresAt).Scan(&key.CreatedAt)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+
+		statement = `
+			SELECT request_hash, status_code, response_headers, response_body, expires_at, created_at
+			FROM idempotency_keys
+			WHERE user_id = $1 AND key = $2
+		`
+		existing = &IdempotencyKey{UserID: key.UserID, Key: key.Key}
+		return tx.QueryRow(ctx, statement, key.UserID, key.Key).Scan(
+			&existing.RequestHash,
+			&existing.StatusCode,
+			&existing.ResponseHeaders,
+			&existing.ResponseBody,
+			&existing.ExpiresAt,
+			&existing.CreatedAt,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, ErrIdempotencyKeyExists
+	}
+	return nil, nil
+}
+
+// Complete stores the response of a reserved key.
+func (i *IdempotencyKeysModel) Complete(ctx context.Context, key *IdempotencyKey) error {
+	statement := `
+		UPDATE idempotency_keys
+		SET status_code = $1, response_headers = $2, response_body = $3
+		WHERE user_id = $4 AND key = $5
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := i.pool.Exec(ctx, statement, key.StatusCode, key.ResponseHeaders, key.ResponseBody, key.UserID, key.Key)
+	return err
+}
+
+// Release gives up a reservation, so the request can be retried with the same
+// key after it failed without a response worth replaying.
+func (i *IdempotencyKeysModel) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	statement := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := i.pool.Exec(ctx, statement, userID, key)
+	return err
+}
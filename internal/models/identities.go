@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdentitiesInterface interface {
+	GetUserID(context.Context, string, string) (uuid.UUID, error)
+	Link(context.Context, *Identity, []byte) error
+	CreateUser(context.Context, *User, *Identity) error
+}
+
+// Identity links an account at an external identity provider, named by its
+// subject identifier there, to a local user.
+type Identity struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type IdentitiesModel struct {
+	pool *pgxpool.Pool
+}
+
+func (i *IdentitiesModel) GetUserID(ctx context.Context, provider, subject string) (uuid.UUID, error) {
+	statement := `SELECT user_id FROM identities WHERE provider = $1 AND subject = $2`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	var userID uuid.UUID
+	err := i.pool.QueryRow(ctx, statement, provider, subject).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrIdentityNotFound
+		}
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// Link attaches identity to an existing user. A user who never activated the
+// account is claimed by the link: whoever signed up with the address did not
+// prove they own it, so the account is activated with placeholderPassword in
+// place of the password chosen at signup, and its sessions are revoked.
+func (i *IdentitiesModel) Link(ctx context.Context, identity *Identity, placeholderPassword []byte) error {
+	return executeWithTx(i.pool, ctx, func(tx pgx.Tx) error {
+		if err := createIdentityTx(ctx, tx, identity); err != nil {
+			return err
+		}
+
+		statement := `UPDATE users SET is_activated = TRUE, password = $2 WHERE id = $1 AND NOT is_activated`
+		result, err := tx.Exec(ctx, statement, identity.UserID, placeholderPassword)
+		if err != nil || result.RowsAffected() == 0 {
+			return err
+		}
+		return revokeAllRefreshTokensTx(ctx, tx, identity.UserID)
+	})
+}
+
+// CreateUser stores a user signing up through an identity provider together
+// with the identity. user.IsActivated is kept as given instead of waiting for
+// an invite.
+func (i *IdentitiesModel) CreateUser(ctx context.Context, user *User, identity *Identity) error {
+	activated := user.IsActivated
+	return executeWithTx(i.pool, ctx, func(tx pgx.Tx) error {
+		if err := createUserTx(ctx, tx, user); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				if pgErr.ConstraintName == "users_username_key" {
+					return ErrDuplicateUsername
+				}
+				return ErrDuplicateEmail
+			}
+			return err
+		}
+		if activated {
+			if _, err := tx.Exec(ctx, `UPDATE users SET is_activated = TRUE WHERE id = $1`, user.ID); err != nil {
+				return err
+			}
+			user.IsActivated = true
+		}
+
+		identity.UserID = user.ID
+		return createIdentityTx(ctx, tx, identity)
+	})
+}
+
+func createIdentityTx(ctx context.Context, tx pgx.Tx, identity *Identity) error {
+	statement := `
+		INSERT INTO identities (id, user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	err := tx.QueryRow(ctx, statement, identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(&identity.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrIdentityAlreadyLinked
+		}
+		return err
+	}
+	return nil
+}
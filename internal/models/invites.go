@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +15,7 @@ const InviteExpirationTime = time.Hour * 12
 type InvitesInterface interface {
 	GetInvite(context.Context, *Invite) error
 	Delete(context.Context, uuid.UUID) error
+	Resend(context.Context, string, time.Duration, int) error
 }
 
 type Invite struct {
@@ -60,6 +62,53 @@ func (i *InvitesModel) GetInvite(ctx context.Context, invite *Invite) error {
 	)
 }
 
+// Resend issues a new token and expiry for the pending invite of the user with
+// the given email and enqueues the invitation email again. last_seen_at marks
+// when the invite was last sent and is used to enforce the cooldown.
+func (i *InvitesModel) Resend(ctx context.Context, email string, cooldown time.Duration, maxSends int) error {
+	return executeWithTx(i.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			SELECT ui.user_id, ui.sent_count, ui.last_seen_at, u.username, u.email
+			FROM user_invites ui
+			JOIN users u ON u.id = ui.user_id
+			WHERE u.email = $1 AND NOT u.is_activated
+			FOR UPDATE OF ui
+		`
+		var invite Invite
+		var event UserInvitedEvent
+		err := tx.QueryRow(ctx, statement, email).Scan(&invite.UserID, &invite.SentCount, &invite.LastSeenAt, &event.Username, &event.Email)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrInviteNotFound
+			}
+			return err
+		}
+
+		if time.Since(invite.LastSeenAt) < cooldown {
+			return ErrInviteCooldown
+		}
+		if invite.SentCount >= maxSends {
+			return ErrInviteLimitReached
+		}
+
+		invite.InviteToken = uuid.New()
+		invite.ExpiresAt = time.Now().Add(InviteExpirationTime)
+		statement = `
+			UPDATE user_invites
+			SET invite_token = $1, expires_at = $2, sent_count = sent_count + 1, last_seen_at = NOW()
+			WHERE user_id = $3
+		`
+		if _, err := tx.Exec(ctx, statement, invite.InviteToken, invite.ExpiresAt, invite.UserID); err != nil {
+			return err
+		}
+
+		event.UserID = invite.UserID
+		event.InviteToken = invite.InviteToken
+		event.ExpiresAt = invite.ExpiresAt
+		return enqueueOutboxTx(ctx, tx, OutboxKindUserInvited, event)
+	})
+}
+
 func createInvite(ctx context.Context, tx pgx.Tx, invite *Invite) error {
 	statement := `
 			INSERT INTO user_invites(user_id, invite_token, expires_at)
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LoginAttemptsInterface keeps failed login counters, keyed by whatever the
+// caller throttles on (an account, an IP address, ...).
+type LoginAttemptsInterface interface {
+	Get(context.Context, string) (*LoginAttempt, error)
+	RecordFailure(context.Context, string, time.Duration) (*LoginAttempt, error)
+	Lock(context.Context, string, time.Time, *AccountLockedEvent) error
+	Clear(context.Context, string) error
+}
+
+type LoginAttempt struct {
+	Key          string     `json:"key"`
+	Failures     int        `json:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+// LoginAttemptsModel stores the counters in Postgres so that every API
+// replica sees the same state.
+type LoginAttemptsModel struct {
+	pool *pgxpool.Pool
+}
+
+// Get returns the counters for key, or a zero LoginAttempt if it never failed.
+func (l *LoginAttemptsModel) Get(ctx context.Context, key string) (*LoginAttempt, error) {
+	statement := `
+		SELECT key, failures, last_failed_at, locked_until
+		FROM login_attempts
+		WHERE key = $1
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	attempt := LoginAttempt{Key: key}
+	err := l.pool.QueryRow(ctx, statement, key).Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailedAt, &attempt.LockedUntil)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// RecordFailure counts one more failure for key. The count starts over when
+// the previous failure is older than window.
+func (l *LoginAttemptsModel) RecordFailure(ctx context.Context, key string, window time.Duration) (*LoginAttempt, error) {
+	statement := `
+		INSERT INTO login_attempts (key, failures, last_failed_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failed_at < NOW() - $2::interval THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failed_at = NOW()
+		RETURNING key, failures, last_failed_at, locked_until
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	var attempt LoginAttempt
+	err := l.pool.QueryRow(ctx, statement, key, window).Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailedAt, &attempt.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Lock refuses logins for key until the given time. When notice is not nil it
+// is enqueued in the same transaction so the owner hears about the lock.
+func (l *LoginAttemptsModel) Lock(ctx context.Context, key string, until time.Time, notice *AccountLockedEvent) error {
+	statement := `
+		INSERT INTO login_attempts (key, locked_until)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET locked_until = EXCLUDED.locked_until
+	`
+	return executeWithTx(l.pool, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+		defer cancel()
+
+		if _, err := tx.Exec(ctx, statement, key, until); err != nil {
+			return err
+		}
+		if notice == nil {
+			return nil
+		}
+		return enqueueOutboxTx(ctx, tx, OutboxKindAccountLocked, *notice)
+	})
+}
+
+func (l *LoginAttemptsModel) Clear(ctx context.Context, key string) error {
+	statement := `DELETE FROM login_attempts WHERE key = $1`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := l.pool.Exec(ctx, statement, key)
+	return err
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// InMemoryLoginAttempts is a process-local LoginAttemptsInterface for tests
+// and single-instance development setups.
+type InMemoryLoginAttempts struct {
+	mu       sync.Mutex
+	attempts map[string]LoginAttempt
+	notices  []AccountLockedEvent
+	now      func() time.Time
+}
+
+func NewInMemoryLoginAttempts() *InMemoryLoginAttempts {
+	return &InMemoryLoginAttempts{
+		attempts: make(map[string]LoginAttempt),
+		now:      time.Now,
+	}
+}
+
+func (l *InMemoryLoginAttempts) Get(_ context.Context, key string) (*LoginAttempt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempt, ok := l.attempts[key]
+	if !ok {
+		attempt = LoginAttempt{Key: key}
+	}
+	return &attempt, nil
+}
+
+func (l *InMemoryLoginAttempts) RecordFailure(_ context.Context, key string, window time.Duration) (*LoginAttempt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	attempt, ok := l.attempts[key]
+	if !ok || attempt.LastFailedAt.Before(now.Add(-window)) {
+		attempt.Key = key
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailedAt = now
+	l.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+// Lock keeps notice in memory instead of enqueueing it; see Notices.
+func (l *InMemoryLoginAttempts) Lock(_ context.Context, key string, until time.Time, notice *AccountLockedEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if notice != nil {
+		l.notices = append(l.notices, *notice)
+	}
+
+	attempt, ok := l.attempts[key]
+	if !ok {
+		attempt = LoginAttempt{Key: key, LastFailedAt: l.now()}
+	}
+	attempt.LockedUntil = &until
+	l.attempts[key] = attempt
+	return nil
+}
+
+func (l *InMemoryLoginAttempts) Clear(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+	return nil
+}
+
+// Notices returns the lock notices passed to Lock, oldest first.
+func (l *InMemoryLoginAttempts) Notices() []AccountLockedEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]AccountLockedEvent(nil), l.notices...)
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MagicLinksInterface interface {
+	Create(context.Context, *User, *MagicLink) error
+	Consume(context.Context, string) (*MagicLink, error)
+}
+
+// MagicLink is a single-use login token sent by email. Like an Invite it
+// belongs to one user and expires, but only its SHA-256 hash is stored. The IP
+// address and user agent that asked for it are kept for auditing only: links
+// are often opened in another browser than the one that requested them.
+type MagicLink struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	Plaintext string     `json:"-"`
+	Hash      []byte     `json:"-"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"user_agent"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+func NewMagicLink(userID uuid.UUID, ip, userAgent string, ttl time.Duration) (*MagicLink, error) {
+	plaintext, hash, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	return &MagicLink{
+		UserID:    userID,
+		Plaintext: plaintext,
+		Hash:      hash,
+		IP:        ip,
+		UserAgent: userAgent,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+type MagicLinksModel struct {
+	pool *pgxpool.Pool
+}
+
+// Create stores the link and enqueues the email carrying it to user in the
+// same transaction.
+func (m *MagicLinksModel) Create(ctx context.Context, user *User, link *MagicLink) error {
+	statement := `
+		INSERT INTO magic_links (token_hash, user_id, ip, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	return executeWithTx(m.pool, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+		defer cancel()
+
+		if err := tx.QueryRow(ctx, statement, link.Hash, link.UserID, link.IP, link.UserAgent, link.ExpiresAt).Scan(&link.CreatedAt); err != nil {
+			return err
+		}
+		return enqueueOutboxTx(ctx, tx, OutboxKindMagicLinkRequested, MagicLinkRequestedEvent{
+			UserID:    user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			Token:     link.Plaintext,
+			ExpiresAt: link.ExpiresAt,
+		})
+	})
+}
+
+// Consume redeems the link when it is unused and unexpired. Redeeming it also
+// voids the user's other outstanding links.
+func (m *MagicLinksModel) Consume(ctx context.Context, plaintext string) (*MagicLink, error) {
+	link := MagicLink{Plaintext: plaintext}
+	err := executeWithTx(m.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			SELECT user_id, ip, user_agent, expires_at, created_at, used_at
+			FROM magic_links
+			WHERE token_hash = $1
+			FOR UPDATE
+		`
+		err := tx.QueryRow(ctx, statement, hashToken(plaintext)).Scan(&link.UserID, &link.IP, &link.UserAgent, &link.ExpiresAt, &link.CreatedAt, &link.UsedAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrMagicLinkInvalid
+			}
+			return err
+		}
+		if link.UsedAt != nil || link.ExpiresAt.Before(time.Now()) {
+			return ErrMagicLinkInvalid
+		}
+
+		statement = `UPDATE magic_links SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+		_, err = tx.Exec(ctx, statement, link.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
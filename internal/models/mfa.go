@@ -0,0 +1,197 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const recoveryCodesCount = 10
+
+type MFAInterface interface {
+	GetTOTP(context.Context, uuid.UUID) (*TOTP, error)
+	EnrolTOTP(context.Context, uuid.UUID, string) error
+	ConfirmTOTP(context.Context, uuid.UUID, int64) ([]string, error)
+	UseTOTPStep(context.Context, uuid.UUID, int64) error
+	UseRecoveryCode(context.Context, uuid.UUID, string) error
+	DisableTOTP(context.Context, uuid.UUID) error
+}
+
+// TOTP is a user's authenticator app enrolment. It only guards logins once
+// ConfirmedAt is set.
+type TOTP struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	Secret       string     `json:"-"`
+	LastUsedStep int64      `json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (t *TOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+type MFAModel struct {
+	pool *pgxpool.Pool
+}
+
+func (m *MFAModel) GetTOTP(ctx context.Context, userID uuid.UUID) (*TOTP, error) {
+	statement := `
+		SELECT user_id, secret, last_used_step, confirmed_at, created_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	var totp TOTP
+	err := m.pool.QueryRow(ctx, statement, userID).Scan(&totp.UserID, &totp.Secret, &totp.LastUsedStep, &totp.ConfirmedAt, &totp.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTOTPNotEnrolled
+		}
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// EnrolTOTP stores a new unconfirmed secret, replacing any earlier
+// unconfirmed one.
+func (m *MFAModel) EnrolTOTP(ctx context.Context, userID uuid.UUID, secret string) error {
+	statement := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	tag, err := m.pool.Exec(ctx, statement, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+// ConfirmTOTP enables the enrolment after the first valid code and returns a
+// fresh set of recovery codes. Only their hashes are stored.
+func (m *MFAModel) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64) ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+
+	err := executeWithTx(m.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			UPDATE user_totp
+			SET confirmed_at = NOW(), last_used_step = $2
+			WHERE user_id = $1 AND confirmed_at IS NULL
+		`
+		tag, err := tx.Exec(ctx, statement, userID, step)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrTOTPAlreadyEnabled
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		for _, code := range codes {
+			statement := `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+			if _, err := tx.Exec(ctx, statement, userID, hashToken(code)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// UseTOTPStep records that a code for the given time step was accepted and
+// fails with ErrTOTPCodeReused if that step, or a later one, already was.
+func (m *MFAModel) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	statement := `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	tag, err := m.pool.Exec(ctx, statement, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPCodeReused
+	}
+	return nil
+}
+
+func (m *MFAModel) UseRecoveryCode(ctx context.Context, userID uuid.UUID, code string) error {
+	statement := `
+		UPDATE recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	tag, err := m.pool.Exec(ctx, statement, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+func (m *MFAModel) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	return executeWithTx(m.pool, ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID)
+		return err
+	})
+}
+
+// generateRecoveryCode returns a code like "k3q9z-m2x7p".
+func generateRecoveryCode() (string, error) {
+	randomBytes := make([]byte, 7)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes))[:10]
+	return encoded[:5] + "-" + encoded[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	if len(code) == 10 {
+		code = code[:5] + "-" + code[5:]
+	}
+	return code
+}
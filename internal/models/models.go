@@ -8,10 +8,26 @@ import (
 )
 
 type Models struct {
-	Posts    PostsInterface
-	Users    UsersInterface
-	Comments CommentsInterface
-	Invites  InvitesInterface
+	Posts                PostsInterface
+	Users                UsersInterface
+	Comments             CommentsInterface
+	Invites              InvitesInterface
+	RefreshTokens        RefreshTokensInterface
+	Sessions             SessionsInterface
+	Outbox               OutboxInterface
+	PasswordResets       PasswordResetsInterface
+	EmailChanges         EmailChangesInterface
+	MFA                  MFAInterface
+	PersonalAccessTokens PersonalAccessTokensInterface
+	Roles                RolesInterface
+	LoginAttempts        LoginAttemptsInterface
+	Identities           IdentitiesInterface
+	OIDCStates           OIDCStatesInterface
+	MagicLinks           MagicLinksInterface
+	IdempotencyKeys      IdempotencyKeysInterface
+	Timelines            TimelinesInterface
+	Reactions            ReactionsInterface
+	Search               SearchInterface
 }
 
 func NewModels(pool *pgxpool.Pool) *Models {
@@ -28,6 +44,54 @@ func NewModels(pool *pgxpool.Pool) *Models {
 		Invites: &InvitesModel{
 			pool: pool,
 		},
+		RefreshTokens: &RefreshTokensModel{
+			pool: pool,
+		},
+		Sessions: &SessionsModel{
+			pool: pool,
+		},
+		Outbox: &OutboxModel{
+			pool: pool,
+		},
+		PasswordResets: &PasswordResetsModel{
+			pool: pool,
+		},
+		EmailChanges: &EmailChangesModel{
+			pool: pool,
+		},
+		MFA: &MFAModel{
+			pool: pool,
+		},
+		PersonalAccessTokens: &PersonalAccessTokensModel{
+			pool: pool,
+		},
+		Roles: &RolesModel{
+			pool: pool,
+		},
+		LoginAttempts: &LoginAttemptsModel{
+			pool: pool,
+		},
+		Identities: &IdentitiesModel{
+			pool: pool,
+		},
+		OIDCStates: &OIDCStatesModel{
+			pool: pool,
+		},
+		MagicLinks: &MagicLinksModel{
+			pool: pool,
+		},
+		IdempotencyKeys: &IdempotencyKeysModel{
+			pool: pool,
+		},
+		Timelines: &TimelinesModel{
+			pool: pool,
+		},
+		Reactions: &ReactionsModel{
+			pool: pool,
+		},
+		Search: &SearchModel{
+			pool: pool,
+		},
 	}
 }
 
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OIDCStatesInterface interface {
+	Create(context.Context, *OIDCState) error
+	Consume(context.Context, string, string) (*OIDCState, error)
+}
+
+// OIDCState remembers a sign-in started at an identity provider until its
+// callback comes back. The state parameter is only stored hashed; the nonce
+// and PKCE verifier are needed in clear to finish the exchange.
+type OIDCState struct {
+	Provider     string    `json:"provider"`
+	Plaintext    string    `json:"-"`
+	Hash         []byte    `json:"-"`
+	Nonce        string    `json:"-"`
+	CodeVerifier string    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewOIDCState generates a fresh state, nonce and PKCE verifier for provider.
+func NewOIDCState(provider string, ttl time.Duration) (*OIDCState, error) {
+	plaintext, hash, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, _, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, _, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCState{
+		Provider:     provider,
+		Plaintext:    plaintext,
+		Hash:         hash,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(ttl),
+	}, nil
+}
+
+type OIDCStatesModel struct {
+	pool *pgxpool.Pool
+}
+
+func (o *OIDCStatesModel) Create(ctx context.Context, state *OIDCState) error {
+	statement := `
+		INSERT INTO oidc_states (state_hash, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	return o.pool.QueryRow(ctx, statement, state.Hash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt).Scan(&state.CreatedAt)
+}
+
+// Consume deletes and returns the state issued for provider, so a callback can
+// only be completed once. Expired states of every provider are swept as well.
+func (o *OIDCStatesModel) Consume(ctx context.Context, provider, plaintext string) (*OIDCState, error) {
+	state := OIDCState{Plaintext: plaintext}
+	err := executeWithTx(o.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			DELETE FROM oidc_states
+			WHERE state_hash = $1
+			RETURNING provider, nonce, code_verifier, expires_at, created_at
+		`
+		err := tx.QueryRow(ctx, statement, hashToken(plaintext)).Scan(&state.Provider, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt, &state.CreatedAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrOIDCStateInvalid
+			}
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM oidc_states WHERE expires_at < NOW()`)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if state.Provider != provider || state.ExpiresAt.Before(time.Now()) {
+		return nil, ErrOIDCStateInvalid
+	}
+	return &state, nil
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	OutboxStatusDead      = "dead"
+)
+
+const (
+	OutboxKindUserInvited    = "user.invited"
+	OutboxKindPostCreated    = "post.created"
+	OutboxKindUserFollowed   = "user.followed"
+	OutboxKindUserUnfollowed = "user.unfollowed"
+
+	OutboxKindPasswordResetRequested = "password_reset.requested"
+	OutboxKindEmailChangeRequested   = "email_change.requested"
+	OutboxKindEmailChangeNotice      = "email_change.notice"
+	OutboxKindMagicLinkRequested     = "magic_link.requested"
+	OutboxKindAccountLocked          = "account.locked"
+)
+
+type OutboxInterface interface {
+	Claim(context.Context, int, time.Duration) ([]OutboxMessage, error)
+	MarkDelivered(context.Context, uuid.UUID) error
+	Retry(context.Context, uuid.UUID, string, time.Time) error
+	DeadLetter(context.Context, uuid.UUID, string) error
+	Purge(context.Context, time.Time) (int64, error)
+}
+
+// OutboxMessage is an email or domain event recorded in the same transaction
+// as the change that produced it, and delivered later by the dispatcher.
+type OutboxMessage struct {
+	ID          uuid.UUID       `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	AvailableAt time.Time       `json:"available_at"`
+	LastError   *string         `json:"last_error"`
+	CreatedAt   time.Time       `json:"created_at"`
+	DeliveredAt *time.Time      `json:"delivered_at"`
+}
+
+// UserInvitedEvent is the payload of OutboxKindUserInvited.
+type UserInvitedEvent struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	InviteToken uuid.UUID `json:"invite_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// PasswordResetRequestedEvent is the payload of
+// OutboxKindPasswordResetRequested. Token is the plaintext reset token; the
+// payload is cleared once delivered.
+type PasswordResetRequestedEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EmailChangeRequestedEvent is the payload of OutboxKindEmailChangeRequested,
+// the confirmation sent to the new address.
+type EmailChangeRequestedEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	NewEmail  string    `json:"new_email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EmailChangeNoticeEvent is the payload of OutboxKindEmailChangeNotice, the
+// heads-up sent to the current address.
+type EmailChangeNoticeEvent struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	NewEmail string    `json:"new_email"`
+}
+
+// MagicLinkRequestedEvent is the payload of OutboxKindMagicLinkRequested.
+type MagicLinkRequestedEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AccountLockedEvent is the payload of OutboxKindAccountLocked, the notice
+// sent to the owner of an account locked after repeated login failures.
+type AccountLockedEvent struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	IP          string    `json:"ip"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// PostCreatedEvent is the payload of OutboxKindPostCreated.
+type PostCreatedEvent struct {
+	PostID uuid.UUID `json:"post_id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// FollowEvent is the payload of OutboxKindUserFollowed and
+// OutboxKindUserUnfollowed. UserID is the followed user.
+type FollowEvent struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowerID uuid.UUID `json:"follower_id"`
+}
+
+type OutboxModel struct {
+	pool *pgxpool.Pool
+}
+
+// Claim leases up to limit pending messages that are due. Claimed messages are
+// hidden from other dispatchers until the lease runs out, so a dispatcher that
+// dies mid-delivery only delays them.
+func (o *OutboxModel) Claim(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	statement := `
+		UPDATE outbox
+		SET attempts = attempts + 1, available_at = NOW() + $2::interval
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = 'pending' AND available_at <= NOW()
+			ORDER BY available_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, payload, status, attempts, available_at, last_error, created_at, delivered_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	rows, err := o.pool.Query(ctx, statement, limit, lease)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var message OutboxMessage
+		err = rows.Scan(
+			&message.ID,
+			&message.Kind,
+			&message.Payload,
+			&message.Status,
+			&message.Attempts,
+			&message.AvailableAt,
+			&message.LastError,
+			&message.CreatedAt,
+			&message.DeliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// MarkDelivered also clears the payload: emails carry single-use tokens that
+// must not outlive their delivery.
+func (o *OutboxModel) MarkDelivered(ctx context.Context, id uuid.UUID) error {
+	statement := `UPDATE outbox SET status = 'delivered', delivered_at = NOW(), last_error = NULL, payload = '{}' WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := o.pool.Exec(ctx, statement, id)
+	return err
+}
+
+func (o *OutboxModel) Retry(ctx context.Context, id uuid.UUID, lastError string, availableAt time.Time) error {
+	statement := `UPDATE outbox SET available_at = $2, last_error = $3 WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := o.pool.Exec(ctx, statement, id, availableAt, lastError)
+	return err
+}
+
+// DeadLetter keeps the payload for diagnosis but strips the single-use tokens
+// emails carry, like MarkDelivered does.
+func (o *OutboxModel) DeadLetter(ctx context.Context, id uuid.UUID, lastError string) error {
+	statement := `UPDATE outbox SET status = 'dead', last_error = $2, payload = payload - 'token' - 'invite_token' WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := o.pool.Exec(ctx, statement, id, lastError)
+	return err
+}
+
+// Purge deletes the delivered and dead-lettered messages created before
+// before, returning how many were deleted.
+func (o *OutboxModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	statement := `DELETE FROM outbox WHERE status IN ('delivered', 'dead') AND created_at < $1`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	result, err := o.pool.Exec(ctx, statement, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func enqueueOutboxTx(ctx context.Context, tx pgx.Tx, kind string, payload any) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	statement := `INSERT INTO outbox (id, kind, payload) VALUES ($1, $2, $3)`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err = tx.Exec(ctx, statement, id, kind, data)
+	return err
+}
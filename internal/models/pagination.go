@@ -1,20 +1,174 @@
 package models
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// PaginatedFeedQuery selects a page of a feed. Pages are addressed either by
+// Offset or, when Cursor is set, by the position of a post (keyset
+// pagination), which stays stable while new posts arrive.
 type PaginatedFeedQuery struct {
 	Limit  int       `json:"limit" validate:"gte=1,lte=20"`
 	Offset int       `json:"offset" validate:"gte=0"`
 	Sort   string    `json:"sort" validate:"oneof=ASC DESC"`
+	Mode   string    `json:"mode" validate:"oneof=latest top"`
 	Tags   []string  `json:"tags" validate:"max=5"`
 	Search string    `json:"search" validate:"max=100"`
 	From   time.Time `json:"from" validate:"lte"`
 	To     time.Time `json:"to" validate:""`
+	// Cursor is the opaque cursor as sent by the client, Position what it
+	// decodes to once verified with DecodeCursor.
+	Cursor   string      `json:"cursor" validate:"max=512"`
+	Position *FeedCursor `json:"-"`
+	// AsOf is the time top mode ranks posts at. It is kept in the cursors of
+	// a ranking so that scores, which decay with time, do not shift between
+	// its pages.
+	AsOf time.Time `json:"-"`
+}
+
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+// Feed modes. Latest orders posts by creation time, top by a time-decayed
+// score of their comments, reactions and the viewer's affinity to the author.
+const (
+	FeedModeLatest = "latest"
+	FeedModeTop    = "top"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// FeedCursor points between two posts of a feed ordered by (created_at, id),
+// or by (score, id) in top mode. Direction tells whether the page wanted
+// comes after or before it, and Sort, Mode and AsOf pin the order the cursor
+// was issued for.
+type FeedCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Direction string    `json:"d"`
+	Sort      string    `json:"s"`
+	Mode      string    `json:"m,omitempty"`
+	Score     float64   `json:"sc,omitempty"`
+	AsOf      time.Time `json:"at,omitzero"`
+}
+
+// Encode serializes the cursor and signs it with secret, so clients cannot
+// forge positions.
+func (c FeedCursor) Encode(secret []byte) string {
+	payload, _ := json.Marshal(c)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signCursor(secret, encoded))
+}
+
+// DecodeCursor verifies pg.Cursor and sets Position from it. The order the
+// cursor was issued for takes precedence over Sort.
+func (pg *PaginatedFeedQuery) DecodeCursor(secret []byte) error {
+	if pg.Cursor == "" {
+		return nil
+	}
+
+	encoded, signature, ok := strings.Cut(pg.Cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, signCursor(secret, encoded)) {
+		return ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	var cursor FeedCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return ErrInvalidCursor
+	}
+	if cursor.Direction != CursorNext && cursor.Direction != CursorPrev {
+		return ErrInvalidCursor
+	}
+	if cursor.Sort != "ASC" && cursor.Sort != "DESC" {
+		return ErrInvalidCursor
+	}
+	switch cursor.Mode {
+	case "":
+		cursor.Mode = FeedModeLatest
+	case FeedModeLatest:
+	case FeedModeTop:
+		if cursor.AsOf.IsZero() {
+			return ErrInvalidCursor
+		}
+	default:
+		return ErrInvalidCursor
+	}
+
+	pg.Position = &cursor
+	pg.Sort = cursor.Sort
+	pg.Mode = cursor.Mode
+	pg.AsOf = cursor.AsOf
+	return nil
+}
+
+func signCursor(secret []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// keyset returns the WHERE condition on (created_at, id), or (score, id) in
+// top mode, selecting the posts past the cursor and the direction to scan
+// them in. Walking backwards scans against the sort order; the caller
+// reverses those rows.
+func (pg PaginatedFeedQuery) keyset(alias string, positionArg, idArg int) (string, string) {
+	column := "created_at"
+	if pg.Mode == FeedModeTop {
+		column = "score"
+	}
+	return pg.keysetOn(alias+"."+column, alias+".id", positionArg, idArg)
+}
+
+// keysetOn is keyset over the given position and id columns, for tables that
+// carry a post's created_at and id under other names.
+func (pg PaginatedFeedQuery) keysetOn(position, id string, positionArg, idArg int) (string, string) {
+	scan := pg.Sort
+	if pg.Position != nil && pg.Position.Direction == CursorPrev {
+		scan = oppositeSort(scan)
+	}
+	if pg.Position == nil {
+		return "", scan
+	}
+	comparison := ">"
+	if scan == "DESC" {
+		comparison = "<"
+	}
+	return "AND (" + position + ", " + id + ") " + comparison + " ($" + strconv.Itoa(positionArg) + ", $" + strconv.Itoa(idArg) + ") ", scan
+}
+
+// keysetArgs returns the cursor's position, matching the columns of keyset.
+func (pg PaginatedFeedQuery) keysetArgs() []any {
+	if pg.Mode == FeedModeTop {
+		return []any{pg.Position.Score, pg.Position.ID}
+	}
+	return []any{pg.Position.CreatedAt, pg.Position.ID}
+}
+
+func oppositeSort(sort string) string {
+	if sort == "ASC" {
+		return "DESC"
+	}
+	return "ASC"
 }
 
 func (pg PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -38,11 +192,19 @@ func (pg PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 		pg.Offset = offset
 	}
 
+	if cursor := queryParams.Get("cursor"); cursor != "" {
+		pg.Cursor = cursor
+	}
+
 	sort := queryParams.Get("sort")
 	if sort != "" {
 		pg.Sort = sort
 	}
 
+	if mode := queryParams.Get("mode"); mode != "" {
+		pg.Mode = mode
+	}
+
 	tags := queryParams.Get("tags")
 	if tags != "" {
 		pg.Tags = strings.Split(tags, ",")
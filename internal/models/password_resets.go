@@ -0,0 +1,128 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PasswordResetsInterface interface {
+	Create(context.Context, *User, time.Duration) (*PasswordReset, error)
+	GetUser(context.Context, string) (*User, error)
+	Reset(context.Context, string, []byte) error
+}
+
+// PasswordReset is a single-use token allowing its owner to choose a new
+// password. Only the SHA-256 hash of the token is stored.
+type PasswordReset struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	Plaintext string     `json:"-"`
+	Hash      []byte     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type PasswordResetsModel struct {
+	pool *pgxpool.Pool
+}
+
+// Create stores a reset token for the user and enqueues the email carrying it
+// in the same transaction.
+func (p *PasswordResetsModel) Create(ctx context.Context, user *User, ttl time.Duration) (*PasswordReset, error) {
+	plaintext, hash, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	reset := &PasswordReset{
+		UserID:    user.ID,
+		Plaintext: plaintext,
+		Hash:      hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	statement := `
+		INSERT INTO password_resets (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+	err = executeWithTx(p.pool, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+		defer cancel()
+
+		if err := tx.QueryRow(ctx, statement, reset.Hash, reset.UserID, reset.ExpiresAt).Scan(&reset.CreatedAt); err != nil {
+			return err
+		}
+		return enqueueOutboxTx(ctx, tx, OutboxKindPasswordResetRequested, PasswordResetRequestedEvent{
+			UserID:    user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			Token:     reset.Plaintext,
+			ExpiresAt: reset.ExpiresAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reset, nil
+}
+
+// GetUser returns the owner of a usable reset token, so the new password can
+// be checked against their details before Reset consumes the token.
+func (p *PasswordResetsModel) GetUser(ctx context.Context, plaintext string) (*User, error) {
+	statement := `
+		SELECT u.id, u.username, u.email
+		FROM password_resets pr
+		JOIN users u ON u.id = pr.user_id
+		WHERE pr.token_hash = $1 AND pr.used_at IS NULL AND pr.expires_at > NOW()
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	var user User
+	err := p.pool.QueryRow(ctx, statement, hashToken(plaintext)).Scan(&user.ID, &user.Username, &user.Email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPasswordResetInvalid
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Reset consumes the token, stores the new password hash, invalidates every
+// other outstanding reset of the user and signs the user out everywhere.
+func (p *PasswordResetsModel) Reset(ctx context.Context, plaintext string, passwordHash []byte) error {
+	return executeWithTx(p.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			SELECT user_id, expires_at, used_at
+			FROM password_resets
+			WHERE token_hash = $1
+			FOR UPDATE
+		`
+		var reset PasswordReset
+		err := tx.QueryRow(ctx, statement, hashToken(plaintext)).Scan(&reset.UserID, &reset.ExpiresAt, &reset.UsedAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrPasswordResetInvalid
+			}
+			return err
+		}
+		if reset.UsedAt != nil || reset.ExpiresAt.Before(time.Now()) {
+			return ErrPasswordResetInvalid
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE users SET password = $1 WHERE id = $2`, passwordHash, reset.UserID); err != nil {
+			return err
+		}
+		statement = `UPDATE password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+		if _, err := tx.Exec(ctx, statement, reset.UserID); err != nil {
+			return err
+		}
+		return revokeAllRefreshTokensTx(ctx, tx, reset.UserID)
+	})
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PersonalAccessTokenPrefix marks personal access tokens so they can be told
+// apart from JWT access tokens in the Authorization header.
+const PersonalAccessTokenPrefix = "social_pat_"
+
+const (
+	ScopePostsRead      = "posts:read"
+	ScopePostsWrite     = "posts:write"
+	ScopeCommentsWrite  = "comments:write"
+	ScopeReactionsWrite = "reactions:write"
+	ScopeFeedRead       = "feed:read"
+	ScopeUsersRead      = "users:read"
+	ScopeUsersWrite     = "users:write"
+	ScopeAdmin          = "admin"
+)
+
+type PersonalAccessTokensInterface interface {
+	Create(context.Context, *PersonalAccessToken) error
+	GetAllForUser(context.Context, uuid.UUID) ([]PersonalAccessToken, error)
+	GetByToken(context.Context, string) (*PersonalAccessToken, error)
+	Delete(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Plaintext  string     `json:"token,omitempty"`
+	Hash       []byte     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func NewPersonalAccessToken(userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*PersonalAccessToken, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	random, _, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	plaintext := PersonalAccessTokenPrefix + random
+	return &PersonalAccessToken{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Plaintext: plaintext,
+		Hash:      hashToken(plaintext),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// HasScope reports whether the token grants scope. The admin scope grants
+// every other scope.
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope) || slices.Contains(t.Scopes, ScopeAdmin)
+}
+
+type PersonalAccessTokensModel struct {
+	pool *pgxpool.Pool
+}
+
+func (p *PersonalAccessTokensModel) Create(ctx context.Context, token *PersonalAccessToken) error {
+	statement := `
+		INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	return p.pool.QueryRow(ctx, statement, token.ID, token.UserID, token.Name, token.Hash, token.Scopes, token.ExpiresAt).Scan(&token.CreatedAt)
+}
+
+func (p *PersonalAccessTokensModel) GetAllForUser(ctx context.Context, userID uuid.UUID) ([]PersonalAccessToken, error) {
+	statement := `
+		SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	rows, err := p.pool.Query(ctx, statement, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []PersonalAccessToken
+	for rows.Next() {
+		var token PersonalAccessToken
+		err = rows.Scan(&token.ID, &token.UserID, &token.Name, &token.Scopes, &token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetByToken looks up an unexpired token by its plaintext value and records
+// that it was used.
+func (p *PersonalAccessTokensModel) GetByToken(ctx context.Context, plaintext string) (*PersonalAccessToken, error) {
+	statement := `
+		UPDATE personal_access_tokens
+		SET last_used_at = NOW()
+		WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING id, user_id, name, scopes, expires_at, last_used_at, created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	var token PersonalAccessToken
+	err := p.pool.QueryRow(ctx, statement, hashToken(plaintext)).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPersonalAccessTokenInvalid
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (p *PersonalAccessTokensModel) Delete(ctx context.Context, userID, tokenID uuid.UUID) error {
+	statement := `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	tag, err := p.pool.Exec(ctx, statement, tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPersonalAccessTokenNotFound
+	}
+	return nil
+}
@@ -2,19 +2,23 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostsInterface interface {
 	Create(ctx context.Context, post *Post) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Post, error)
-	Delete(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID, version int) error
 	Update(ctx context.Context, post *Post) error
-	Feed(ctx context.Context, userID uuid.UUID, pg PaginatedFeedQuery) ([]FeedPost, error)
+	Feed(ctx context.Context, userID uuid.UUID, pg PaginatedFeedQuery) ([]FeedPost, bool, error)
+	Public(ctx context.Context, viewerID uuid.UUID, pg PaginatedFeedQuery) ([]FeedPost, bool, error)
 }
 
 type Post struct {
@@ -33,27 +37,34 @@ type Post struct {
 type FeedPost struct {
 	Post
 	CommentsCount     int        `json:"comments_count"`
+	ReactionsCount    int        `json:"reactions_count"`
 	TopCommentContent *string    `json:"top_comment_content"`
 	TopCommentUserID  *uuid.UUID `json:"top_comment_user_id"`
+	// Score is the post's rank in the top feed, and nil in other modes.
+	Score *float64 `json:"score,omitempty"`
 }
 
 type PostsModel struct {
 	pool *pgxpool.Pool
 }
 
+// Create stores the post and enqueues its fan-out to the followers'
+// timelines in the same transaction.
 func (p *PostsModel) Create(ctx context.Context, post *Post) error {
 	statement := `
 			INSERT INTO posts (id, title, content, user_id, tags)
 			VALUES ($1, $2, $3, $4, $5)
 			RETURNING created_at, updated_at
 		`
-	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
-	defer cancel()
-	err := p.pool.QueryRow(ctx, statement, post.ID, post.Title, post.Content, post.UserID, post.Tags).Scan(&post.CreatedAt, &post.UpdatedAt)
-	if err != nil {
-		return err
-	}
-	return nil
+	return executeWithTx(p.pool, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+		defer cancel()
+		err := tx.QueryRow(ctx, statement, post.ID, post.Title, post.Content, post.UserID, post.Tags).Scan(&post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return enqueueOutboxTx(ctx, tx, OutboxKindPostCreated, PostCreatedEvent{PostID: post.ID, UserID: post.UserID})
+	})
 }
 
 func (p *PostsModel) GetByID(ctx context.Context, id uuid.UUID) (*Post, error) {
@@ -71,12 +82,25 @@ func (p *PostsModel) GetByID(ctx context.Context, id uuid.UUID) (*Post, error) {
 	return post, nil
 }
 
-func (p *PostsModel) Delete(ctx context.Context, id uuid.UUID) error {
-	statement := `DELETE FROM posts WHERE id = $1`
-	_, err := p.pool.Exec(ctx, statement, id)
-	return err
+// Delete removes the post if it is still at version, returning ErrEditConflict
+// when it has been changed or removed since.
+func (p *PostsModel) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	statement := `DELETE FROM posts WHERE id = $1 AND version = $2`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	result, err := p.pool.Exec(ctx, statement, id, version)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrEditConflict
+	}
+	return nil
 }
 
+// Update saves the post if it is still at post.Version and bumps the version,
+// returning ErrEditConflict when it has been changed or removed since.
 func (p *PostsModel) Update(ctx context.Context, post *Post) error {
 	statement := `
 		UPDATE posts
@@ -86,50 +110,230 @@ func (p *PostsModel) Update(ctx context.Context, post *Post) error {
 	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
 	defer cancel()
 	err := p.pool.QueryRow(ctx, statement, post.Title, post.Content, post.Tags, post.ID, post.Version).Scan(&post.Version)
-	return err
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// feedAffinity counts the viewer's ($1) comments and reactions on the posts
+// of every other author, for the top feed.
+const feedAffinity = `
+		WITH affinity AS (
+			SELECT ap.user_id AS author_id, COUNT(*) AS interactions
+			FROM (
+				SELECT post_id FROM comments WHERE user_id = $1
+				UNION ALL
+				SELECT post_id FROM post_reactions WHERE user_id = $1
+			) i
+			JOIN posts ap ON ap.id = i.post_id
+			WHERE ap.user_id <> $1
+			GROUP BY ap.user_id
+		)`
+
+// topFeedWindow is how far back from its AsOf the top feed ranks posts. Older
+// posts have decayed too far to rank, and leaving them out spares every page
+// from counting the comments and reactions of the whole history in scope.
+const topFeedWindow = 7 * 24 * time.Hour
+
+// feedScore ranks a post for the top feed at the time in the argument it is
+// formatted with: engagement, with reactions weighing twice a comment, grows
+// logarithmically with the viewer's affinity to the author and decays with
+// the post's age in hours.
+const feedScore = `(1 + cc.comments_count + 2 * rc.reactions_count)::float8
+			* (1 + LN(1 + COALESCE(a.interactions, 0)::float8))
+			/ POWER(GREATEST(EXTRACT(EPOCH FROM ($%[1]d::timestamptz - p.created_at))::float8 / 3600, 0) + 2, 1.5)`
+
+// feedPage describes the page listFeed is after to its source, so a source
+// made of several branches can select and cut each one short on its own.
+type feedPage struct {
+	// filters are the search, tag and date conditions on p.
+	filters string
+	// keyset returns the cursor condition over the given created_at and id
+	// columns. It is empty without a cursor and in top mode.
+	keyset func(createdAt, id string) string
+	// scan is the direction the page is read in.
+	scan string
+	// rows is how many rows lead up to the end of the page.
+	rows string
+	// window returns the top feed's topFeedWindow condition over the given
+	// created_at column, which filters also hold over p. It is empty in other
+	// modes.
+	window func(createdAt string) string
+	// top is set for the top feed, which ranks every post in scope.
+	top bool
 }
 
-func (p *PostsModel) Feed(ctx context.Context, userID uuid.UUID, pg PaginatedFeedQuery) ([]FeedPost, error) {
+// feedSource returns the FROM item listFeed reads the posts of a feed from, as
+// p.
+type feedSource func(page feedPage) string
+
+// Feed returns a page of posts by the user and the users they follow, and
+// whether more posts follow in the direction the page was requested in.
+// Followed posts come from the user's materialized timeline, walked along its
+// (user_id, created_at, post_id) index, except those that were not fanned out,
+// which are merged in from posts. Each branch stops at the end of the page.
+// The top feed ranks every post in its window, so it reads the branches whole.
+func (p *PostsModel) Feed(ctx context.Context, userID uuid.UUID, pg PaginatedFeedQuery) ([]FeedPost, bool, error) {
+	source := func(page feedPage) string {
+		order := func(createdAt, id string) string {
+			if page.top {
+				return ""
+			}
+			return "ORDER BY " + createdAt + " " + page.scan + ", " + id + " " + page.scan + " LIMIT " + page.rows
+		}
+		return `(
+			(
+				SELECT t.post_id AS id
+				FROM timelines t
+				JOIN posts p ON p.id = t.post_id
+				WHERE t.user_id = $1 ` + page.window("t.created_at") + page.filters + page.keyset("t.created_at", "t.post_id") +
+			order("t.created_at", "t.post_id") + `
+			)
+			UNION
+			(
+				SELECT p.id
+				FROM posts p
+				WHERE p.user_id = $1 ` + page.filters + page.keyset("p.created_at", "p.id") +
+			order("p.created_at", "p.id") + `
+			)
+			UNION
+			(
+				SELECT cp.id
+				FROM followers f
+				CROSS JOIN LATERAL (
+					SELECT p.id
+					FROM posts p
+					WHERE p.user_id = f.user_id AND NOT p.fanned_out ` + page.filters + page.keyset("p.created_at", "p.id") +
+			order("p.created_at", "p.id") + `
+				) cp
+				WHERE f.follower_id = $1
+			)
+		) page
+		JOIN posts p ON p.id = page.id`
+	}
+	return p.listFeed(ctx, userID, source, pg)
+}
+
+// Public returns a page of posts by anyone, as seen by viewerID, and whether
+// more posts follow in the direction the page was requested in.
+func (p *PostsModel) Public(ctx context.Context, viewerID uuid.UUID, pg PaginatedFeedQuery) ([]FeedPost, bool, error) {
+	return p.listFeed(ctx, viewerID, func(feedPage) string { return "posts p" }, pg)
+}
+
+// listFeed returns the page of posts read from source and matching pg for
+// viewerID ($1). In top mode posts are ranked by score at pg.AsOf, ties broken
+// by id. Only posts from the topFeedWindow before pg.AsOf are ranked; later
+// ones are left out so the ranking does not shift between pages.
+func (p *PostsModel) listFeed(ctx context.Context, viewerID uuid.UUID, source feedSource, pg PaginatedFeedQuery) ([]FeedPost, bool, error) {
 	extraWhereArguments := ""
-	args := []interface{}{userID, pg.Limit, pg.Offset}
-	argID := 4
+	// one row more than asked for tells whether there is a further page
+	args := []any{viewerID, pg.Limit + 1}
+	argID := 3
 
 	if pg.Search != "" {
-		extraWhereArguments += fmt.Sprintf("AND (p.title ILIKE $%d OR p.content ILIKE $%d)", argID, argID+1)
+		extraWhereArguments += fmt.Sprintf("AND (p.title ILIKE $%d OR p.content ILIKE $%d) ", argID, argID+1)
 		args = append(args, "%"+pg.Search+"%", "%"+pg.Search+"%")
 		argID += 2
 	}
 
 	if pg.Tags != nil {
-		extraWhereArguments += fmt.Sprintf("AND p.tags @> $%d", argID)
+		extraWhereArguments += fmt.Sprintf("AND p.tags @> $%d ", argID)
 		args = append(args, pg.Tags)
 		argID++
 	}
 
 	if !pg.From.IsZero() && !pg.To.IsZero() {
-		extraWhereArguments += fmt.Sprintf("AND p.created_at BETWEEN $%d AND $%d", argID, argID+1)
+		extraWhereArguments += fmt.Sprintf("AND p.created_at BETWEEN $%d AND $%d ", argID, argID+1)
 		args = append(args, pg.From, pg.To)
 		argID += 2
 	}
 
-	statement := `
+	top := pg.Mode == FeedModeTop
+	score, affinityJoin := "NULL::float8", ""
+	window := func(string) string { return "" }
+	if top {
+		score = fmt.Sprintf(feedScore, argID)
+		affinityJoin = "LEFT JOIN affinity a ON a.author_id = p.user_id"
+		asOfArg, sinceArg := argID, argID+1
+		window = func(createdAt string) string {
+			return fmt.Sprintf("AND %[1]s <= $%[2]d AND %[1]s > $%[3]d ", createdAt, asOfArg, sinceArg)
+		}
+		extraWhereArguments += window("p.created_at")
+		args = append(args, pg.AsOf, pg.AsOf.Add(-topFeedWindow))
+		argID += 2
+	}
+
+	alias := "p"
+	if top {
+		alias = "f"
+	}
+	keysetWhere, scan := pg.keyset(alias, argID, argID+1)
+	page := feedPage{
+		filters: extraWhereArguments,
+		keyset:  func(string, string) string { return "" },
+		window:  window,
+		scan:    scan,
+		rows:    "$2",
+		top:     top,
+	}
+	offset := ""
+	if pg.Position != nil {
+		if !top {
+			positionArg, idArg := argID, argID+1
+			page.keyset = func(createdAt, id string) string {
+				where, _ := pg.keysetOn(createdAt, id, positionArg, idArg)
+				return where
+			}
+		}
+		args = append(args, pg.keysetArgs()...)
+	} else {
+		offset = fmt.Sprintf("OFFSET $%d", argID)
+		page.rows = fmt.Sprintf("($2::bigint + $%d::bigint)", argID)
+		args = append(args, pg.Offset)
+	}
+
+	selection := `
 		SELECT p.id, p.title, p.content, p.user_id, p.tags, p.created_at,
-       (SELECT COUNT(*) FROM comments WHERE post_id = p.id) AS comments_count,
-       u.username, u.id,
-       (SELECT content FROM comments WHERE post_id = p.id ORDER BY created_at DESC limit 1) AS top_comment,
-       (SELECT user_id FROM comments WHERE post_id = p.id ORDER BY created_at DESC LIMIT 1) AS top_comment_user_id
-		FROM posts p
+       cc.comments_count, rc.reactions_count,
+       u.username, u.id AS author_id,
+       tc.content AS top_comment,
+       tc.user_id AS top_comment_user_id,
+       ` + score + ` AS score
+		FROM ` + source(page) + `
 		JOIN users u ON p.user_id = u.id
-		WHERE (p.user_id = $1 OR p.user_id IN (SELECT user_id from followers WHERE follower_id = $1)) ` + extraWhereArguments + `
-		ORDER BY p.created_at ` + pg.Sort + `
-		LIMIT $2 offset $3;
+		LEFT JOIN LATERAL (SELECT COUNT(*) AS comments_count FROM comments WHERE post_id = p.id) cc ON TRUE
+		LEFT JOIN LATERAL (SELECT COUNT(*) AS reactions_count FROM post_reactions WHERE post_id = p.id) rc ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT content, user_id FROM comments WHERE post_id = p.id ORDER BY created_at DESC LIMIT 1
+		) tc ON TRUE
+		` + affinityJoin + `
+		WHERE TRUE ` + extraWhereArguments
+
+	var statement string
+	if top {
+		statement = feedAffinity + `
+		SELECT * FROM (` + selection + `
+		) f
+		WHERE TRUE ` + keysetWhere + `
+		ORDER BY f.score ` + scan + `, f.id ` + scan + `
+		LIMIT $2 ` + offset + `;
+	`
+	} else {
+		statement = selection + keysetWhere + `
+		ORDER BY p.created_at ` + scan + `, p.id ` + scan + `
+		LIMIT $2 ` + offset + `;
 	`
+	}
 	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
 	defer cancel()
 
 	rows, err := p.pool.Query(ctx, statement, args...)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
@@ -144,15 +348,28 @@ func (p *PostsModel) Feed(ctx context.Context, userID uuid.UUID, pg PaginatedFee
 			&feedPost.Tags,
 			&feedPost.CreatedAt,
 			&feedPost.CommentsCount,
+			&feedPost.ReactionsCount,
 			&feedPost.User.Username,
 			&feedPost.User.ID,
 			&feedPost.TopCommentContent,
 			&feedPost.TopCommentUserID,
+			&feedPost.Score,
 		)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		feed = append(feed, feedPost)
 	}
-	return feed, nil
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	more := len(feed) > pg.Limit
+	if more {
+		feed = feed[:pg.Limit]
+	}
+	if pg.Position != nil && pg.Position.Direction == CursorPrev {
+		slices.Reverse(feed)
+	}
+	return feed, more, nil
 }
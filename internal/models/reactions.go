@@ -0,0 +1,48 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReactionsInterface records which users reacted to a post. A user reacts to
+// a post at most once; reacting again is a no-op.
+type ReactionsInterface interface {
+	React(ctx context.Context, postID, userID uuid.UUID) error
+	Unreact(ctx context.Context, postID, userID uuid.UUID) error
+}
+
+type ReactionsModel struct {
+	pool *pgxpool.Pool
+}
+
+func (r *ReactionsModel) React(ctx context.Context, postID, userID uuid.UUID) error {
+	statement := `INSERT INTO post_reactions (post_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx, statement, postID, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return ErrForeignKeyViolation
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *ReactionsModel) Unreact(ctx context.Context, postID, userID uuid.UUID) error {
+	statement := `DELETE FROM post_reactions WHERE post_id = $1 AND user_id = $2`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx, statement, postID, userID)
+	return err
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RefreshTokensInterface interface {
+	Rotate(context.Context, string, time.Duration) (*RefreshToken, error)
+	RevokeFamily(context.Context, string) error
+	RevokeAllForUser(context.Context, uuid.UUID) error
+}
+
+// RefreshToken is a single link in a rotation chain. Every token minted by
+// rotating another one shares its FamilyID, which is also the ID of the
+// session the chain belongs to, so the whole chain can be revoked at once
+// when a rotated token is presented again.
+type RefreshToken struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	FamilyID  uuid.UUID  `json:"family_id"`
+	Plaintext string     `json:"-"`
+	Hash      []byte     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+func NewRefreshToken(userID, familyID uuid.UUID, ttl time.Duration) (*RefreshToken, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, hash, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	return &RefreshToken{
+		ID:        id,
+		UserID:    userID,
+		FamilyID:  familyID,
+		Plaintext: plaintext,
+		Hash:      hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+type RefreshTokensModel struct {
+	pool *pgxpool.Pool
+}
+
+// Rotate consumes the refresh token and issues its successor in the same
+// family. Presenting a token that was already rotated or revoked revokes the
+// whole family and returns ErrRefreshTokenReused.
+func (rt *RefreshTokensModel) Rotate(ctx context.Context, plaintext string, ttl time.Duration) (*RefreshToken, error) {
+	var next *RefreshToken
+	reused := false
+
+	err := executeWithTx(rt.pool, ctx, func(tx pgx.Tx) error {
+		current, err := getRefreshTokenForUpdate(ctx, tx, hashToken(plaintext))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+
+		if current.RotatedAt != nil || current.RevokedAt != nil {
+			reused = true
+			return revokeRefreshTokenFamily(ctx, tx, current.FamilyID)
+		}
+		if current.ExpiresAt.Before(time.Now()) {
+			return ErrRefreshTokenInvalid
+		}
+
+		statement := `UPDATE refresh_tokens SET rotated_at = NOW() WHERE id = $1`
+		if _, err := tx.Exec(ctx, statement, current.ID); err != nil {
+			return err
+		}
+
+		if err := touchSessionTx(ctx, tx, current.FamilyID); err != nil {
+			return err
+		}
+
+		next, err = NewRefreshToken(current.UserID, current.FamilyID, ttl)
+		if err != nil {
+			return err
+		}
+		return createRefreshTokenTx(ctx, tx, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+	return next, nil
+}
+
+// RevokeFamily revokes every token of the family the given token belongs to,
+// along with the session it was issued for. Unknown tokens are ignored.
+func (rt *RefreshTokensModel) RevokeFamily(ctx context.Context, plaintext string) error {
+	return executeWithTx(rt.pool, ctx, func(tx pgx.Tx) error {
+		current, err := getRefreshTokenForUpdate(ctx, tx, hashToken(plaintext))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		return revokeRefreshTokenFamily(ctx, tx, current.FamilyID)
+	})
+}
+
+func (rt *RefreshTokensModel) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	return executeWithTx(rt.pool, ctx, func(tx pgx.Tx) error {
+		return revokeAllRefreshTokensTx(ctx, tx, userID)
+	})
+}
+
+func getRefreshTokenForUpdate(ctx context.Context, tx pgx.Tx, hash []byte) (*RefreshToken, error) {
+	statement := `
+		SELECT id, user_id, family_id, token_hash, expires_at, created_at, rotated_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	var token RefreshToken
+	err := tx.QueryRow(ctx, statement, hash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.Hash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.RotatedAt,
+		&token.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func createRefreshTokenTx(ctx context.Context, tx pgx.Tx, token *RefreshToken) error {
+	statement := `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	return tx.QueryRow(ctx, statement, token.ID, token.UserID, token.FamilyID, token.Hash, token.ExpiresAt).Scan(&token.CreatedAt)
+}
+
+func revokeRefreshTokenFamily(ctx context.Context, tx pgx.Tx, familyID uuid.UUID) error {
+	statement := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	if _, err := tx.Exec(ctx, statement, familyID); err != nil {
+		return err
+	}
+	return revokeSessionTx(ctx, tx, familyID)
+}
+
+func revokeAllRefreshTokensTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	statements := []string{
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`,
+		`UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`,
+	}
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+type RolesInterface interface {
+	GetByName(context.Context, string) (*Role, error)
+}
+
+// Role grants everything granted to roles with a lower Level.
+type Role struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Level       int    `json:"level"`
+	Description string `json:"description"`
+}
+
+type RolesModel struct {
+	pool *pgxpool.Pool
+}
+
+func (r *RolesModel) GetByName(ctx context.Context, name string) (*Role, error) {
+	statement := `
+		SELECT id, name, level, COALESCE(description, '')
+		FROM roles
+		WHERE name = $1
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	var role Role
+	err := r.pool.QueryRow(ctx, statement, name).Scan(&role.ID, &role.Name, &role.Level, &role.Description)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	SearchTypePosts    = "posts"
+	SearchTypeComments = "comments"
+	SearchTypeUsers    = "users"
+)
+
+// searchHeadlineOptions marks the matched words in snippets with <mark> tags.
+// The text is passed through html_escape first so these are its only tags.
+const searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10"
+
+// SearchQuery is a full-text search. Query accepts web search syntax:
+// quoted phrases, OR and -excluded words.
+type SearchQuery struct {
+	Query  string `json:"q" validate:"required,max=200"`
+	Type   string `json:"type" validate:"oneof=posts comments users"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=20"`
+	Offset int    `json:"offset" validate:"gte=0"`
+}
+
+// SearchInterface searches posts, comments and users, best matches first.
+type SearchInterface interface {
+	Posts(ctx context.Context, q SearchQuery) ([]SearchResult, error)
+	Comments(ctx context.Context, q SearchQuery) ([]SearchResult, error)
+	Users(ctx context.Context, q SearchQuery) ([]SearchResult, error)
+}
+
+// SearchResult is a match of any search type. Snippet is an HTML fragment: the
+// HTML-escaped matching text with the matched words in <mark> tags. UserID and
+// Username are the author of a post or comment, or the matched user.
+type SearchResult struct {
+	ID        uuid.UUID  `json:"id"`
+	Type      string     `json:"type"`
+	Rank      float32    `json:"rank"`
+	Snippet   string     `json:"snippet"`
+	Title     *string    `json:"title,omitempty"`
+	PostID    *uuid.UUID `json:"post_id,omitempty"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Username  string     `json:"username"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type SearchModel struct {
+	pool *pgxpool.Pool
+}
+
+func (s *SearchModel) Posts(ctx context.Context, q SearchQuery) ([]SearchResult, error) {
+	statement := `
+		SELECT p.id, ts_rank(p.search_vector, query) AS rank,
+			ts_headline('english', html_escape(p.content), query, $4), p.title, NULL::uuid, u.id, u.username, p.created_at
+		FROM posts p
+		JOIN users u ON u.id = p.user_id,
+			websearch_to_tsquery('english', $1) query
+		WHERE p.search_vector @@ query
+		ORDER BY rank DESC, p.id DESC
+		LIMIT $2 OFFSET $3`
+	return s.search(ctx, SearchTypePosts, statement, q)
+}
+
+func (s *SearchModel) Comments(ctx context.Context, q SearchQuery) ([]SearchResult, error) {
+	statement := `
+		SELECT c.id, ts_rank(c.search_vector, query) AS rank,
+			ts_headline('english', html_escape(c.content), query, $4), NULL::text, c.post_id, u.id, u.username, c.created_at
+		FROM comments c
+		JOIN users u ON u.id = c.user_id,
+			websearch_to_tsquery('english', $1) query
+		WHERE c.search_vector @@ query
+		ORDER BY rank DESC, c.id DESC
+		LIMIT $2 OFFSET $3`
+	return s.search(ctx, SearchTypeComments, statement, q)
+}
+
+// Users only matches activated users.
+func (s *SearchModel) Users(ctx context.Context, q SearchQuery) ([]SearchResult, error) {
+	statement := `
+		SELECT u.id, ts_rank(u.search_vector, query) AS rank,
+			ts_headline('simple', html_escape(u.username), query, $4), NULL::text, NULL::uuid, u.id, u.username, u.created_at
+		FROM users u,
+			websearch_to_tsquery('simple', $1) query
+		WHERE u.search_vector @@ query AND u.is_activated
+		ORDER BY rank DESC, u.id DESC
+		LIMIT $2 OFFSET $3`
+	return s.search(ctx, SearchTypeUsers, statement, q)
+}
+
+func (s *SearchModel) search(ctx context.Context, searchType, statement string, q SearchQuery) ([]SearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, statement, q.Query, q.Limit, q.Offset, searchHeadlineOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		result := SearchResult{Type: searchType}
+		err = rows.Scan(
+			&result.ID,
+			&result.Rank,
+			&result.Snippet,
+			&result.Title,
+			&result.PostID,
+			&result.UserID,
+			&result.Username,
+			&result.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+func (q SearchQuery) Parse(r *http.Request) (SearchQuery, error) {
+	queryParams := r.URL.Query()
+
+	q.Query = queryParams.Get("q")
+
+	if searchType := queryParams.Get("type"); searchType != "" {
+		q.Type = searchType
+	}
+
+	limitString := queryParams.Get("limit")
+	if limitString != "" {
+		limit, err := strconv.Atoi(limitString)
+		if err != nil {
+			return SearchQuery{}, err
+		}
+		q.Limit = limit
+	}
+
+	offsetString := queryParams.Get("offset")
+	if offsetString != "" {
+		offset, err := strconv.Atoi(offsetString)
+		if err != nil {
+			return SearchQuery{}, err
+		}
+		q.Offset = offset
+	}
+
+	return q, nil
+}
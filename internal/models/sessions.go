@@ -0,0 +1,175 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// sessionTouchInterval bounds how often an authenticated request writes the
+// last_used_at column of its session.
+const sessionTouchInterval = time.Minute
+
+type SessionsInterface interface {
+	Create(context.Context, *Session, *RefreshToken) error
+	GetAllForUser(context.Context, uuid.UUID) ([]Session, error)
+	Touch(context.Context, uuid.UUID, uuid.UUID) error
+	Revoke(context.Context, uuid.UUID, uuid.UUID) error
+	RevokeOthers(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+// Session represents a single sign-in on a device. Its ID doubles as the
+// family ID of the refresh tokens rotated from that sign-in.
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current"`
+}
+
+type SessionsModel struct {
+	pool *pgxpool.Pool
+}
+
+func (s *SessionsModel) Create(ctx context.Context, session *Session, refreshToken *RefreshToken) error {
+	return executeWithTx(s.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			INSERT INTO sessions (id, user_id, user_agent, ip)
+			VALUES ($1, $2, $3, $4)
+			RETURNING created_at, last_used_at
+		`
+		err := tx.QueryRow(ctx, statement, session.ID, session.UserID, session.UserAgent, session.IP).Scan(&session.CreatedAt, &session.LastUsedAt)
+		if err != nil {
+			return err
+		}
+
+		refreshToken.FamilyID = session.ID
+		return createRefreshTokenTx(ctx, tx, refreshToken)
+	})
+}
+
+func (s *SessionsModel) GetAllForUser(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	statement := `
+		SELECT id, user_id, user_agent, ip, created_at, last_used_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_used_at DESC
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, statement, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+		err = rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Touch verifies that the session is still active and bumps its last_used_at
+// at most once per sessionTouchInterval.
+func (s *SessionsModel) Touch(ctx context.Context, sessionID, userID uuid.UUID) error {
+	statement := `
+		SELECT last_used_at, revoked_at
+		FROM sessions
+		WHERE id = $1 AND user_id = $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	var lastUsedAt time.Time
+	var revokedAt *time.Time
+	err := s.pool.QueryRow(ctx, statement, sessionID, userID).Scan(&lastUsedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	if revokedAt != nil {
+		return ErrSessionRevoked
+	}
+	if time.Since(lastUsedAt) < sessionTouchInterval {
+		return nil
+	}
+
+	_, err = s.pool.Exec(ctx, `UPDATE sessions SET last_used_at = NOW() WHERE id = $1`, sessionID)
+	return err
+}
+
+func (s *SessionsModel) Revoke(ctx context.Context, userID, sessionID uuid.UUID) error {
+	return executeWithTx(s.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			SELECT EXISTS (SELECT 1 FROM sessions WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL)
+		`
+		var exists bool
+		if err := tx.QueryRow(ctx, statement, sessionID, userID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrSessionNotFound
+		}
+		return revokeRefreshTokenFamily(ctx, tx, sessionID)
+	})
+}
+
+func (s *SessionsModel) RevokeOthers(ctx context.Context, userID, currentSessionID uuid.UUID) error {
+	return executeWithTx(s.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			SELECT id FROM sessions
+			WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+			FOR UPDATE
+		`
+		rows, err := tx.Query(ctx, statement, userID, currentSessionID)
+		if err != nil {
+			return err
+		}
+		sessionIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			return err
+		}
+
+		for _, sessionID := range sessionIDs {
+			if err := revokeRefreshTokenFamily(ctx, tx, sessionID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func revokeSessionTx(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID) error {
+	statement := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := tx.Exec(ctx, statement, sessionID)
+	return err
+}
+
+func touchSessionTx(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID) error {
+	statement := `UPDATE sessions SET last_used_at = NOW() WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := tx.Exec(ctx, statement, sessionID)
+	return err
+}
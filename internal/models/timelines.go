@@ -0,0 +1,81 @@
+package models
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TimelinesInterface maintains the materialized home timelines read by
+// PostsModel.Feed. Posts are fanned out to followers when written, except for
+// authors with at least celebrityThreshold followers at the time: their posts
+// stay marked as not fanned out and are merged in when the feed is read.
+type TimelinesInterface interface {
+	FanOut(ctx context.Context, postID uuid.UUID, celebrityThreshold int) error
+	Backfill(ctx context.Context, userID, followeeID uuid.UUID, limit, celebrityThreshold int) error
+	Prune(ctx context.Context, userID, followeeID uuid.UUID) error
+}
+
+type TimelinesModel struct {
+	pool *pgxpool.Pool
+}
+
+// FanOut adds the post to the timeline of every follower of its author and
+// marks it as fanned out.
+func (t *TimelinesModel) FanOut(ctx context.Context, postID uuid.UUID, celebrityThreshold int) error {
+	statement := `
+		WITH post AS (
+			UPDATE posts p SET fanned_out = TRUE
+			FROM users u
+			WHERE p.id = $1 AND u.id = p.user_id AND u.followers_count < $2
+			RETURNING p.id, p.user_id, p.created_at
+		)
+		INSERT INTO timelines (user_id, post_id, author_id, created_at)
+		SELECT f.follower_id, post.id, post.user_id, post.created_at
+		FROM post
+		JOIN followers f ON f.user_id = post.user_id
+		ON CONFLICT DO NOTHING
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := t.pool.Exec(ctx, statement, postID, celebrityThreshold)
+	return err
+}
+
+// Backfill copies the latest posts of followeeID into the timeline of userID
+// after a follow. Nothing is copied if the follow has been undone meanwhile.
+func (t *TimelinesModel) Backfill(ctx context.Context, userID, followeeID uuid.UUID, limit, celebrityThreshold int) error {
+	statement := `
+		INSERT INTO timelines (user_id, post_id, author_id, created_at)
+		SELECT $1, p.id, p.user_id, p.created_at
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.user_id = $2 AND u.followers_count < $4
+			AND EXISTS (SELECT 1 FROM followers WHERE user_id = $2 AND follower_id = $1)
+		ORDER BY p.created_at DESC
+		LIMIT $3
+		ON CONFLICT DO NOTHING
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := t.pool.Exec(ctx, statement, userID, followeeID, limit, celebrityThreshold)
+	return err
+}
+
+// Prune removes the posts of followeeID from the timeline of userID after an
+// unfollow, unless the user has followed again meanwhile.
+func (t *TimelinesModel) Prune(ctx context.Context, userID, followeeID uuid.UUID) error {
+	statement := `
+		DELETE FROM timelines
+		WHERE user_id = $1 AND author_id = $2
+			AND NOT EXISTS (SELECT 1 FROM followers WHERE user_id = $2 AND follower_id = $1)
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	_, err := t.pool.Exec(ctx, statement, userID, followeeID)
+	return err
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// generateToken returns a random URL-safe token together with the SHA-256
+// hash that gets persisted in its place.
+func generateToken() (string, []byte, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", nil, err
+	}
+	plaintext := base64.RawURLEncoding.EncodeToString(randomBytes)
+	return plaintext, hashToken(plaintext), nil
+}
+
+func hashToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
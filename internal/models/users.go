@@ -14,10 +14,13 @@ import (
 type UsersInterface interface {
 	Create(context.Context, *User) error
 	Get(context.Context, uuid.UUID) (*User, error)
+	GetByEmail(context.Context, string) (*User, error)
 	Update(context.Context, *User) error
+	UpdatePassword(context.Context, uuid.UUID, []byte) error
 	Follow(context.Context, uuid.UUID, uuid.UUID) error
 	Unfollow(context.Context, uuid.UUID, uuid.UUID) error
 	CreateUserAndInvite(context.Context, *User) error
+	ActivateByInviteToken(context.Context, uuid.UUID) error
 }
 
 type User struct {
@@ -27,6 +30,7 @@ type User struct {
 	Password    string    `json:"-"`
 	CreatedAt   time.Time `json:"created_at"`
 	IsActivated bool      `json:"is_activated"`
+	Role        *Role     `json:"role,omitempty"`
 }
 
 type UserModel struct {
@@ -47,16 +51,28 @@ func (u *UserModel) Create(ctx context.Context, user *User) error {
 
 func (u *UserModel) Get(ctx context.Context, userID uuid.UUID) (*User, error) {
 	statement := `
-		SELECT users.ID, USERNAME, EMAIL, PASSWORD, CREATED_AT
+		SELECT users.ID, USERNAME, EMAIL, PASSWORD, CREATED_AT, IS_ACTIVATED, roles.id, roles.name, roles.level, COALESCE(roles.description, '')
 		FROM users
-		WHERE id = $1
+		JOIN roles ON roles.id = users.role_id
+		WHERE users.id = $1
 	`
 	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
 	defer cancel()
 
-	var user User
+	user := User{Role: &Role{}}
 	var passwordBytes []byte
-	err := u.pool.QueryRow(ctx, statement, userID).Scan(&user.ID, &user.Username, &user.Email, &passwordBytes, &user.CreatedAt)
+	err := u.pool.QueryRow(ctx, statement, userID).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&passwordBytes,
+		&user.CreatedAt,
+		&user.IsActivated,
+		&user.Role.ID,
+		&user.Role.Name,
+		&user.Role.Level,
+		&user.Role.Description,
+	)
 	user.Password = string(passwordBytes)
 	if err != nil {
 		return nil, err
@@ -64,48 +80,93 @@ func (u *UserModel) Get(ctx context.Context, userID uuid.UUID) (*User, error) {
 	return &user, nil
 }
 
+func (u *UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	statement := `
+		SELECT id, username, email, password, created_at, is_activated
+		FROM users
+		WHERE email = $1
+	`
+	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+	defer cancel()
+
+	var user User
+	var passwordBytes []byte
+	err := u.pool.QueryRow(ctx, statement, email).Scan(&user.ID, &user.Username, &user.Email, &passwordBytes, &user.CreatedAt, &user.IsActivated)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = string(passwordBytes)
+	return &user, nil
+}
+
+// Update saves the user's profile fields. The email is deliberately left out:
+// it only changes through a confirmed EmailChange.
 func (u *UserModel) Update(ctx context.Context, user *User) error {
 	statement := `
 		UPDATE users u 
-		SET username = $1, is_activated = $2, email = $3
-		WHERE u.id = $4
+		SET username = $1, is_activated = $2
+		WHERE u.id = $3
 `
 
 	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
 	defer cancel()
 
-	_, err := u.pool.Exec(ctx, statement, user.Username, user.IsActivated, user.Email, user.ID)
+	_, err := u.pool.Exec(ctx, statement, user.Username, user.IsActivated, user.ID)
 	return err
 }
 
-func (u *UserModel) Follow(ctx context.Context, userID uuid.UUID, followerID uuid.UUID) error {
-	statement := `INSERT INTO followers(user_id, follower_id) VALUES ($1, $2)`
+func (u *UserModel) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash []byte) error {
+	statement := `UPDATE users SET password = $1 WHERE id = $2`
+
 	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
 	defer cancel()
 
-	_, err := u.pool.Exec(ctx, statement, userID, followerID)
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23503" || pgErr.Code == "23505" {
-				return ErrForeignKeyViolation
+	_, err := u.pool.Exec(ctx, statement, passwordHash, userID)
+	return err
+}
+
+// Follow records the follow and enqueues the back-fill of the follower's
+// timeline in the same transaction.
+func (u *UserModel) Follow(ctx context.Context, userID uuid.UUID, followerID uuid.UUID) error {
+	statement := `INSERT INTO followers(user_id, follower_id) VALUES ($1, $2)`
+	return executeWithTx(u.pool, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+		defer cancel()
+
+		_, err := tx.Exec(ctx, statement, userID, followerID)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				if pgErr.Code == "23503" || pgErr.Code == "23505" {
+					return ErrForeignKeyViolation
+				}
 			}
+			return err
 		}
-		return err
-	}
 
-	return nil
+		return enqueueOutboxTx(ctx, tx, OutboxKindUserFollowed, FollowEvent{UserID: userID, FollowerID: followerID})
+	})
 }
 
+// Unfollow removes the follow and enqueues the pruning of the follower's
+// timeline in the same transaction.
 func (u *UserModel) Unfollow(ctx context.Context, userID uuid.UUID, followerID uuid.UUID) error {
 	statement := `DELETE from followers WHERE user_id = $1 AND follower_id = $2`
-	ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
-	defer cancel()
+	return executeWithTx(u.pool, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, maxQueryDuration)
+		defer cancel()
 
-	_, err := u.pool.Exec(ctx, statement, userID, followerID)
-	return err
+		result, err := tx.Exec(ctx, statement, userID, followerID)
+		if err != nil || result.RowsAffected() == 0 {
+			return err
+		}
+
+		return enqueueOutboxTx(ctx, tx, OutboxKindUserUnfollowed, FollowEvent{UserID: userID, FollowerID: followerID})
+	})
 }
 
+// CreateUserAndInvite stores the user with a fresh invite and enqueues the
+// invitation email in the same transaction.
 func (u *UserModel) CreateUserAndInvite(ctx context.Context, user *User) error {
 	return executeWithTx(u.pool, ctx, func(tx pgx.Tx) error {
 		err := createUserTx(ctx, tx, user)
@@ -117,7 +178,46 @@ func (u *UserModel) CreateUserAndInvite(ctx context.Context, user *User) error {
 			InviteToken: uuid.New(),
 			ExpiresAt:   time.Now().Add(InviteExpirationTime),
 		}
-		return createInvite(ctx, tx, invite)
+		if err := createInvite(ctx, tx, invite); err != nil {
+			return err
+		}
+		return enqueueOutboxTx(ctx, tx, OutboxKindUserInvited, UserInvitedEvent{
+			UserID:      user.ID,
+			Username:    user.Username,
+			Email:       user.Email,
+			InviteToken: invite.InviteToken,
+			ExpiresAt:   invite.ExpiresAt,
+		})
+	})
+}
+
+// ActivateByInviteToken activates the owner of the invite and consumes the
+// invite in a single transaction.
+func (u *UserModel) ActivateByInviteToken(ctx context.Context, token uuid.UUID) error {
+	return executeWithTx(u.pool, ctx, func(tx pgx.Tx) error {
+		statement := `
+			SELECT user_id, expires_at
+			FROM user_invites
+			WHERE invite_token = $1
+			FOR UPDATE
+		`
+		var invite Invite
+		err := tx.QueryRow(ctx, statement, token).Scan(&invite.UserID, &invite.ExpiresAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrInviteNotFound
+			}
+			return err
+		}
+		if invite.ExpiresAt.Before(time.Now()) {
+			return ErrInviteExpired
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE users SET is_activated = TRUE WHERE id = $1`, invite.UserID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM user_invites WHERE user_id = $1`, invite.UserID)
+		return err
 	})
 }
 